@@ -1,12 +1,21 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/samber/mo"
 
+	"dev/internal/cache"
+	"dev/internal/config"
+	"dev/internal/daemon"
 	"dev/internal/filesystem"
+	"dev/internal/history"
 	"dev/internal/projects"
+	"dev/internal/registry"
 	"dev/internal/terminal"
 	"dev/internal/tui"
 )
@@ -14,37 +23,112 @@ import (
 type Flags struct {
 	PrintPath     bool
 	NoUpdateTitle bool
+	MaxDepth      mo.Option[int]
+	Markers       []string
+	Nested        bool
+	NoWorkspaces  bool
+	Refresh       bool
+	ScanTimeout   time.Duration
+	Excludes      []string
+	HiddenDirs    []string
+	Hidden        bool
+	Follow        bool
+	Diagnose      bool
+	Jobs          int
+	NoGitInfo     bool
+	NoStatus      bool
+	Sort          projects.SortMode
+	// Profile is the profile chosen with --profile. Some("") chooses none,
+	// even when DEV_PROFILE is set.
+	Profile mo.Option[string]
 }
 
+// statusWorkers is how many `git status` run at once while the picker is
+// open.
+const statusWorkers = 4
+
+// rereadWorkers is how many projects listed before the picker opened are
+// read in again at once, which is a few small file reads each.
+const rereadWorkers = 8
+
+// cacheSaveTimeout bounds how long dev waits on exit for the scans it
+// stopped to be cached.
+const cacheSaveTimeout = time.Second
+
 type Config struct {
-	Args  []string
+	// Args are the arguments left after the options: search paths, or what
+	// a command acts on.
+	Args []string
+	// Paths are the search paths in effect, which Configure takes from Args
+	// or else from the profile, the environment or config.toml.
+	Paths []string
 	Flags Flags
 	Icons tui.Icons
+	Keys  tui.KeyMap
 	Term  terminal.Terminal
 	Fs    filesystem.FileSystem
+	// File is what config.toml sets, applied by Configure.
+	File config.File
+	// sources says where Configure took each search setting from.
+	sources map[string]string
+	// profile is the chosen profile, or "" for none.
+	profile string
+	// unconfigured is cfg as it was before Configure, to configure it
+	// again for another profile.
+	unconfigured *Config
 }
 
 func Run(cfg Config) mo.Result[string] {
-	projectsResult, err := projects.Discover(cfg.Fs, cfg.Args).Get()
+	if cfg.Flags.Diagnose {
+		return diagnose(cfg)
+	}
+
+	// Scans of every profile listed keep going while the editor is open so
+	// their caches are complete for next time. Once dev is done they are
+	// stopped, and what they found so far is cached before it exits.
+	var saves sync.WaitGroup
+	defer waitAtMost(&saves, cacheSaveTimeout)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := listing(ctx, cfg, &saves).Get()
 	if err != nil {
 		return mo.Err[string](err)
 	}
-	if len(projectsResult) == 0 {
-		return mo.Err[string](fmt.Errorf("no projects found"))
+
+	configs, err := profileConfigs(cfg).Get()
+	if err != nil {
+		return mo.Err[string](err)
 	}
 
-	model := tui.NewModel(projectsResult, tui.DefaultKeyMap(), cfg.Icons)
+	model := newModel(cfg, l).
+		WithProfiles(profileNames(cfg), cfg.profile, func(name string) mo.Result[tui.Listing] {
+			return listing(ctx, configs[name], &saves)
+		})
+
+	statusCtx, stopStatus := context.WithCancel(context.Background())
+	model = model.WithReread(projects.NewCollector(statusCtx, reread(cfg), func(projects.Project) bool { return true }, rereadWorkers))
+	if !cfg.Flags.NoStatus {
+		model = model.WithStatus(projects.NewStatusCollector(statusCtx, projects.GitStatus, statusWorkers))
+	}
 
 	tuiResult, err := tui.Run(model).Get()
+	stopStatus()
 	if err != nil {
 		return mo.Err[string](err)
 	}
 
+	// The project is opened the way the profile it was picked from says.
+	cfg = configs[tuiResult.Profile]
+
 	_, err = cfg.Fs.Chdir(tuiResult.Path).Get()
 	if err != nil {
 		return mo.Err[string](err)
 	}
 
+	// A failure to remember the selection is not worth failing it over.
+	_ = history.Record(tuiResult.Path)
+
 	if cfg.Flags.PrintPath {
 		return mo.Ok(tuiResult.Path)
 	}
@@ -54,6 +138,14 @@ func Run(cfg Config) mo.Result[string] {
 		_ = cfg.Term.RenameTab(title)
 	}
 
+	// A multiplexer may open the editor in place of dev's pane, which ends
+	// dev, so the scans are cached first. Only an editor dev runs itself
+	// leaves them time to finish.
+	if _, ok := cfg.Term.(*terminal.Default); !ok {
+		cancel()
+		waitAtMost(&saves, cacheSaveTimeout)
+	}
+
 	_, err = cfg.Term.OpenEditor(tuiResult.Path).Get()
 	if err != nil {
 		return mo.Err[string](err)
@@ -61,3 +153,114 @@ func Run(cfg Config) mo.Result[string] {
 
 	return mo.Ok("")
 }
+
+// listing lists the projects cfg searches for: the ones a daemon keeps, or
+// else the cached ones while a scan, stopped with ctx, finds them again.
+// The scan is cached once it completes or is stopped, which saves tracks.
+func listing(ctx context.Context, cfg Config, saves *sync.WaitGroup) mo.Result[tui.Listing] {
+	key := cacheKey(cfg)
+	if indexed, err := daemon.Query(key).Get(); err == nil && !cfg.Flags.Refresh {
+		// A daemon keeps the list current, so there is nothing to scan.
+		return mo.Ok(tui.Listing{Projects: indexed})
+	}
+
+	ctx, cancel := scanContext(ctx, cfg.Flags.ScanTimeout)
+	scan, err := projects.Stream(ctx, cfg.Fs, cfg.Paths, scanOptions(cfg)).Get()
+	if err != nil {
+		cancel()
+		return mo.Err[tui.Listing](err)
+	}
+
+	cached := cache.Load(key).OrEmpty()
+	saves.Go(func() {
+		defer cancel()
+		_ = cache.Update(key, scan, cached)
+	})
+
+	if cfg.Flags.Refresh {
+		cached = nil
+	}
+	return mo.Ok(tui.Listing{Projects: cached, Scan: scan})
+}
+
+// waitAtMost waits for wg, but no longer than timeout.
+func waitAtMost(wg *sync.WaitGroup, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// reread reads in again what may have changed about a project since it was
+// cached or indexed, such as its branch.
+func reread(cfg Config) func(context.Context, projects.Project) mo.Result[projects.Project] {
+	return func(_ context.Context, p projects.Project) mo.Result[projects.Project] {
+		return mo.Ok(projects.Reread(cfg.Fs, p, !cfg.Flags.NoGitInfo))
+	}
+}
+
+// newModel lists l together with the registered projects, in the order and
+// with the frecency from cfg. What may have changed about them since they
+// were found is read in again once the picker is open.
+func newModel(cfg Config, l tui.Listing) tui.Model {
+	// A registry that cannot be read adds and hides nothing rather than
+	// keeping dev from starting.
+	r := registry.Load().OrEmpty()
+
+	visits := history.Load().OrElse(projects.Visits{})
+	return tui.NewModel(nil, cfg.Keys, cfg.Icons).
+		WithRegistered(registered(cfg, r)).
+		WithHidden(r.Hidden, setHidden).
+		WithSort(cfg.Flags.Sort).
+		WithFrecency(visits.Scores(time.Now())).
+		WithListing(l)
+}
+
+func scanOptions(cfg Config) projects.Options {
+	return projects.Options{
+		MaxDepth:       cfg.Flags.MaxDepth,
+		Markers:        cfg.Flags.Markers,
+		Nested:         cfg.Flags.Nested,
+		SkipWorkspaces: cfg.Flags.NoWorkspaces,
+		Excludes:       cfg.Flags.Excludes,
+		HiddenDirs:     cfg.Flags.HiddenDirs,
+		Hidden:         cfg.Flags.Hidden,
+		FollowSymlinks: cfg.Flags.Follow,
+		Concurrency:    cfg.Flags.Jobs,
+		ReadGitInfo:    !cfg.Flags.NoGitInfo,
+		ReadActivity:   true,
+	}
+}
+
+func scanContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// cacheKey identifies everything that decides which projects a scan finds,
+// so that different search paths or options never share a cached list.
+func cacheKey(cfg Config) string {
+	return fmt.Sprintf("%q %v %q %t %t %q %q %t %t %q %q %q %q %q",
+		cfg.Paths,
+		cfg.Flags.MaxDepth.OrEmpty(),
+		cfg.Flags.Markers,
+		cfg.Flags.Nested,
+		cfg.Flags.NoWorkspaces,
+		cfg.Flags.Excludes,
+		cfg.Flags.HiddenDirs,
+		cfg.Flags.Hidden,
+		cfg.Flags.Follow,
+		os.Getenv("DEV_PATHS"),
+		os.Getenv("DEV_MAX_DEPTH"),
+		os.Getenv("DEV_MARKERS"),
+		os.Getenv("DEV_EXCLUDE"),
+		os.Getenv("DEV_HIDDEN_DIRS"),
+	)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"dev/internal/cache"
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+	"dev/internal/tui"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// hangingFileSystem is the real file system, except that reading hang
+// blocks like a stuck network mount.
+type hangingFileSystem struct {
+	filesystem.RealFileSystem
+	hang    string
+	release chan struct{}
+}
+
+func (fs *hangingFileSystem) ReadDir(path string) mo.Result[[]os.DirEntry] {
+	if path == fs.hang {
+		<-fs.release
+	}
+	return fs.RealFileSystem.ReadDir(path)
+}
+
+func TestListing_CachesScanStoppedMidWalk(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	root := t.TempDir()
+	for _, dir := range []string{"fast/.git", "nfs/remote/.git"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	cfg := Configure(Config{
+		Args: []string{root},
+		Keys: tui.DefaultKeyMap(),
+		Fs:   &hangingFileSystem{hang: filepath.Join(root, "nfs"), release: release},
+	}).MustGet()
+
+	var saves sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	l, err := listing(ctx, cfg, &saves).Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Picked before the scan ends, as soon as it lists a project.
+	if found, _ := l.Scan.Next(0); len(found) == 0 {
+		t.Fatal("expected the scan to find a project")
+	}
+	cancel()
+	saves.Wait()
+
+	got := lo.Map(cache.Load(cacheKey(cfg)).OrEmpty(), func(p projects.Project, _ int) string { return p.Path })
+	if want := []string{filepath.Join(root, "fast")}; !slices.Equal(got, want) {
+		t.Errorf("expected %v cached, got %v", want, got)
+	}
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+
+	"dev/internal/projects"
+	"dev/internal/terminal"
+	"dev/internal/tui"
+)
+
+// Where an effective setting comes from, in order of precedence.
+const (
+	fromFlag    = "flag"
+	fromEnv     = "environment"
+	fromFile    = "config.toml"
+	fromDefault = "default"
+)
+
+// Configure fills in cfg.Paths and what cfg.Flags leave unset from cfg.Args,
+// the chosen profile, the environment and then config.toml, and applies the
+// keys, theme, icons and terminal settings of config.toml. Everything else
+// falls back to the defaults. A profile is chosen explicitly, so what it
+// sets wins over the environment.
+func Configure(cfg Config) mo.Result[Config] {
+	unconfigured := cfg
+	cfg.unconfigured = &unconfigured
+	file := cfg.File
+	cfg.sources = make(map[string]string)
+
+	if name, ok := cfg.Flags.Profile.Get(); ok {
+		cfg.profile, cfg.sources["profile"] = name, fromFlag
+	} else if name := os.Getenv("DEV_PROFILE"); name != "" {
+		cfg.profile, cfg.sources["profile"] = name, fromEnv
+	} else {
+		cfg.sources["profile"] = fromDefault
+	}
+	profile, ok := file.Profiles[cfg.profile]
+	if cfg.profile != "" && !ok {
+		return mo.Err[Config](fmt.Errorf("unknown profile %q", cfg.profile))
+	}
+
+	cfg.Paths = cfg.pick("paths", cfg.Args, profile.Paths, "DEV_PATHS", file.Paths)
+	cfg.Flags.Markers = cfg.pick("markers", cfg.Flags.Markers, profile.Markers, "DEV_MARKERS", file.Markers)
+	cfg.Flags.Excludes = cfg.pick("excludes", cfg.Flags.Excludes, profile.Excludes, "DEV_EXCLUDE", file.Excludes)
+	cfg.Flags.HiddenDirs = cfg.pick("hidden_dirs", cfg.Flags.HiddenDirs, profile.HiddenDirs, "DEV_HIDDEN_DIRS", file.HiddenDirs)
+
+	switch {
+	case cfg.Flags.MaxDepth.IsPresent():
+		cfg.sources["max_depth"] = fromFlag
+	case profile.MaxDepth.IsPresent():
+		cfg.Flags.MaxDepth = profile.MaxDepth
+		cfg.sources["max_depth"] = cfg.profileSource()
+	case os.Getenv("DEV_MAX_DEPTH") != "":
+		// Left to the scan, which reports it when it is invalid.
+		cfg.sources["max_depth"] = fromEnv
+	case file.MaxDepth.IsPresent():
+		cfg.Flags.MaxDepth = file.MaxDepth
+		cfg.sources["max_depth"] = fromFile
+	default:
+		cfg.sources["max_depth"] = fromDefault
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(file.Keys)) {
+		if err := cfg.Keys.Rebind(name, file.Keys[name]); err != nil {
+			return mo.Err[Config](fmt.Errorf("%s: %w", file.Path, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(file.Theme)) {
+		if err := tui.SetColor(name, file.Theme[name]); err != nil {
+			return mo.Err[Config](fmt.Errorf("%s: %w", file.Path, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(file.Icons)) {
+		if err := cfg.Icons.Set(name, file.Icons[name]); err != nil {
+			return mo.Err[Config](fmt.Errorf("%s: %w", file.Path, err))
+		}
+	}
+
+	term, err := terminal.Detect(terminalOptions(cfg)).Get()
+	if err != nil {
+		return mo.Err[Config](fmt.Errorf("%s: %w", file.Path, err))
+	}
+	cfg.Term = term
+
+	return mo.Ok(cfg)
+}
+
+// withProfile configures cfg again as if name had been chosen with
+// --profile. The empty name is the configuration without a profile.
+func withProfile(cfg Config, name string) mo.Result[Config] {
+	next := *cfg.unconfigured
+	next.Flags.Profile = mo.Some(name)
+	return Configure(next)
+}
+
+// profileConfigs configures cfg for each of its profiles, keyed by name.
+// Every profile is configured up front, since Configure applies the theme
+// and the picker lists profiles concurrently.
+func profileConfigs(cfg Config) mo.Result[map[string]Config] {
+	configs := map[string]Config{cfg.profile: cfg}
+	for _, name := range profileNames(cfg) {
+		if _, ok := configs[name]; ok {
+			continue
+		}
+		next, err := withProfile(cfg, name).Get()
+		if err != nil {
+			return mo.Err[map[string]Config](err)
+		}
+		configs[name] = next
+	}
+	return mo.Ok(configs)
+}
+
+// profileNames returns the profiles to cycle through, starting with the
+// configuration without a profile, or nothing when there are no profiles.
+func profileNames(cfg Config) []string {
+	if len(cfg.File.Profiles) == 0 {
+		return nil
+	}
+	return append([]string{""}, slices.Sorted(maps.Keys(cfg.File.Profiles))...)
+}
+
+// pick returns the first of flag, profile, the words of the environment
+// variable env and file that sets anything, and notes where it came from
+// as the source of key.
+func (cfg *Config) pick(key string, flag, profile []string, env string, file []string) []string {
+	switch {
+	case len(flag) > 0:
+		cfg.sources[key] = fromFlag
+		return flag
+	case len(profile) > 0:
+		cfg.sources[key] = cfg.profileSource()
+		return profile
+	case os.Getenv(env) != "":
+		cfg.sources[key] = fromEnv
+		return strings.Fields(os.Getenv(env))
+	case len(file) > 0:
+		cfg.sources[key] = fromFile
+		return file
+	}
+	cfg.sources[key] = fromDefault
+	return nil
+}
+
+func (cfg *Config) profileSource() string {
+	return "profile " + cfg.profile
+}
+
+func terminalOptions(cfg Config) terminal.Options {
+	opts := terminal.Options{
+		Editor:        cfg.File.Editor,
+		ProfileEditor: cfg.File.Profiles[cfg.profile].Editor,
+		Launch:        make(map[string]string),
+		KeepTabName:   make(map[string]bool),
+	}
+	for name, t := range cfg.File.Terminals {
+		if t.Launch != "" {
+			opts.Launch[name] = t.Launch
+		}
+		if rename, ok := t.RenameTab.Get(); ok {
+			opts.KeepTabName[name] = !rename
+		}
+	}
+	return opts
+}
+
+// ConfigShow prints the effective configuration as config.toml would set
+// it, with where each search setting comes from.
+func ConfigShow(cfg Config) mo.Result[string] {
+	return mo.Ok(formatConfig(cfg))
+}
+
+func formatConfig(cfg Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", cfg.File.Path)
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	setting := func(key, value string) {
+		fmt.Fprintf(w, "%s = %s\t# %s\n", key, value, cfg.sources[key])
+	}
+
+	if len(cfg.File.Profiles) > 0 {
+		setting("profile", tomlString(cfg.profile))
+	}
+
+	paths := cfg.Paths
+	if len(paths) == 0 {
+		home, _ := os.UserHomeDir()
+		paths = []string{home}
+	}
+	setting("paths", tomlList(paths))
+
+	depth := strconv.Itoa(cfg.Flags.MaxDepth.OrElse(projects.DefaultMaxDepth))
+	if cfg.sources["max_depth"] == fromEnv {
+		depth = os.Getenv("DEV_MAX_DEPTH")
+	}
+	setting("max_depth", depth)
+	setting("excludes", tomlList(cfg.Flags.Excludes))
+	setting("markers", tomlList(cfg.Flags.Markers))
+	setting("hidden_dirs", tomlList(cfg.Flags.HiddenDirs))
+
+	editor, source := terminal.Editor(terminalOptions(cfg))
+	switch {
+	case editor == "":
+		source = "not set"
+	case source == "profile":
+		source = cfg.profileSource()
+	case source == "":
+		source = fromFile
+	}
+	fmt.Fprintf(w, "editor = %s\t# %s\n", tomlString(editor), source)
+	_ = w.Flush()
+
+	writeTable(&b, "keys", lo.MapValues(cfg.Keys.Named(), func(keys []string, _ string) string {
+		return tomlList(keys)
+	}))
+	writeTable(&b, "theme", lo.MapValues(tui.Colors(), func(c, _ string) string {
+		return tomlString(c)
+	}))
+	writeTable(&b, "icons", lo.MapValues(cfg.Icons.Named(), func(icon, _ string) string {
+		return tomlString(icon)
+	}))
+
+	opts := terminalOptions(cfg)
+	for _, name := range slices.Sorted(maps.Keys(terminal.Launches)) {
+		writeTable(&b, "terminal."+name, map[string]string{
+			"launch":     tomlString(lo.CoalesceOrEmpty(opts.Launch[name], terminal.Launches[name][0])),
+			"rename_tab": strconv.FormatBool(!opts.KeepTabName[name]),
+		})
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeTable(b *strings.Builder, name string, values map[string]string) {
+	fmt.Fprintf(b, "\n[%s]\n", name)
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(b, "%s = %s\n", key, values[key])
+	}
+}
+
+func tomlList(values []string) string {
+	return "[" + strings.Join(lo.Map(values, func(v string, _ int) string {
+		return tomlString(v)
+	}), ", ") + "]"
+}
+
+// tomlString quotes s as a TOML basic string. Unlike strconv.Quote it keeps
+// icons from private use areas, such as nerd font glyphs, as they are.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
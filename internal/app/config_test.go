@@ -0,0 +1,251 @@
+package app
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"dev/internal/config"
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+	"dev/internal/terminal"
+	"dev/internal/tui"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// clearEnv unsets everything Configure reads from the environment.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"DEV_PATHS", "DEV_MAX_DEPTH", "DEV_MARKERS", "DEV_EXCLUDE", "DEV_HIDDEN_DIRS", "DEV_PROFILE", "EDITOR", "VISUAL", "TMUX", "ZELLIJ"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConfigure_Precedence(t *testing.T) {
+	file := config.File{
+		Search: config.Search{
+			Paths:      []string{"/file"},
+			MaxDepth:   mo.Some(1),
+			Excludes:   []string{"file"},
+			Markers:    []string{"file"},
+			HiddenDirs: []string{".file"},
+		},
+		Profiles: map[string]config.Profile{
+			"work": {Search: config.Search{
+				Paths:      []string{"/profile"},
+				MaxDepth:   mo.Some(2),
+				Excludes:   []string{"profile"},
+				Markers:    []string{"profile"},
+				HiddenDirs: []string{".profile"},
+			}},
+		},
+	}
+	env := map[string]string{
+		"DEV_PATHS":       "/env",
+		"DEV_MAX_DEPTH":   "3",
+		"DEV_EXCLUDE":     "env",
+		"DEV_MARKERS":     "env",
+		"DEV_HIDDEN_DIRS": ".env",
+	}
+
+	tests := []struct {
+		name    string
+		flags   bool
+		profile bool
+		env     bool
+		file    bool
+		want    string
+		source  string
+	}{
+		{name: "flag", flags: true, profile: true, env: true, file: true, want: "flag", source: "flag"},
+		{name: "profile", profile: true, env: true, file: true, want: "profile", source: "profile work"},
+		{name: "environment", env: true, file: true, want: "env", source: "environment"},
+		{name: "config.toml", file: true, want: "file", source: "config.toml"},
+		{name: "default", source: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			cfg := Config{Keys: tui.DefaultKeyMap(), Fs: &filesystem.RealFileSystem{}}
+			if tt.flags {
+				cfg.Args = []string{"/flag"}
+				cfg.Flags.MaxDepth = mo.Some(4)
+				cfg.Flags.Excludes = []string{"flag"}
+				cfg.Flags.Markers = []string{"flag"}
+				cfg.Flags.HiddenDirs = []string{".flag"}
+			}
+			if tt.profile {
+				cfg.Flags.Profile = mo.Some("work")
+			}
+			if tt.env {
+				for name, value := range env {
+					t.Setenv(name, value)
+				}
+			}
+			if tt.file {
+				cfg.File = file
+			}
+
+			got, err := Configure(cfg).Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want [][]string
+			if tt.want != "" {
+				want = [][]string{{"/" + tt.want}, {tt.want}, {tt.want}, {"." + tt.want}}
+			}
+			for i, list := range [][]string{got.Paths, got.Flags.Excludes, got.Flags.Markers, got.Flags.HiddenDirs} {
+				if want != nil && !slices.Equal(list, want[i]) {
+					t.Errorf("expected %v, got %v", want[i], list)
+				}
+				if want == nil && len(list) != 0 {
+					t.Errorf("expected nothing set, got %v", list)
+				}
+			}
+			wantDepth := map[string]mo.Option[int]{"flag": mo.Some(4), "profile": mo.Some(2), "env": mo.None[int](), "file": mo.Some(1), "": mo.None[int]()}[tt.want]
+			if got.Flags.MaxDepth != wantDepth {
+				t.Errorf("expected max depth %v, got %v", wantDepth, got.Flags.MaxDepth)
+			}
+
+			shown := formatConfig(got)
+			for _, key := range []string{"paths", "max_depth", "excludes", "markers", "hidden_dirs"} {
+				line := regexp.MustCompile(`(?m)^` + key + ` = .*# (.*)$`).FindStringSubmatch(shown)
+				if line == nil || line[1] != tt.source {
+					t.Errorf("expected config show to say %s comes from %q, got:\n%s", key, tt.source, shown)
+				}
+			}
+			if tt.want == "env" && !strings.Contains(shown, "max_depth = 3") {
+				t.Errorf("expected config show to print the max depth from the environment, got:\n%s", shown)
+			}
+		})
+	}
+}
+
+func TestConfigure_ChoosesProfile(t *testing.T) {
+	file := config.File{
+		Search: config.Search{Paths: []string{"/file"}},
+		Profiles: map[string]config.Profile{
+			"work": {Search: config.Search{Paths: []string{"/work"}}},
+			"home": {Search: config.Search{Paths: []string{"/home"}}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		flag    mo.Option[string]
+		env     string
+		want    string
+		source  string
+		wantErr bool
+	}{
+		{name: "none", want: "", source: "default"},
+		{name: "environment", env: "home", want: "home", source: "environment"},
+		{name: "flag", flag: mo.Some("work"), want: "work", source: "flag"},
+		{name: "flag over environment", flag: mo.Some("work"), env: "home", want: "work", source: "flag"},
+		{name: "empty flag chooses none", flag: mo.Some(""), env: "home", want: "", source: "flag"},
+		{name: "unknown flag", flag: mo.Some("play"), wantErr: true},
+		{name: "unknown environment", env: "play", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DEV_PROFILE", tt.env)
+			t.Setenv("DEV_PATHS", "/env")
+
+			cfg, err := Configure(Config{File: file, Flags: Flags{Profile: tt.flag}, Keys: tui.DefaultKeyMap()}).Get()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "unknown profile") {
+					t.Errorf("expected an unknown profile error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.profile != tt.want || cfg.sources["profile"] != tt.source {
+				t.Errorf("expected profile %q from %s, got %q from %s", tt.want, tt.source, cfg.profile, cfg.sources["profile"])
+			}
+			// The profile's paths win over DEV_PATHS, which wins over the file.
+			wantPaths := lo.Ternary(tt.want == "", []string{"/env"}, []string{"/" + tt.want})
+			if !slices.Equal(cfg.Paths, wantPaths) {
+				t.Errorf("expected paths %v, got %v", wantPaths, cfg.Paths)
+			}
+		})
+	}
+}
+
+func TestProfileConfigs_ListAndOpenTheProfileWay(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	home, work := t.TempDir(), t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(home, "dotfiles", ".git"),
+		filepath.Join(work, "api", ".git"),
+		filepath.Join(work, "archive", "legacy", ".git"),
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := Configure(Config{
+		Keys: tui.DefaultKeyMap(),
+		Fs:   &filesystem.RealFileSystem{},
+		File: config.File{
+			Search: config.Search{Paths: []string{home}},
+			Editor: "vi",
+			Profiles: map[string]config.Profile{
+				"work": {Search: config.Search{Paths: []string{work}, Excludes: []string{"archive"}}, Editor: "code --wait"},
+			},
+		},
+	}).MustGet()
+
+	configs, err := profileConfigs(cfg).Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(maps.Keys(configs)); !slices.Equal(got, []string{"", "work"}) {
+		t.Fatalf("expected a configuration without a profile and one for work, got %v", got)
+	}
+
+	tests := []struct {
+		profile string
+		want    []string
+		editor  string
+	}{
+		{profile: "", want: []string{filepath.Join(home, "dotfiles")}, editor: "vi"},
+		{profile: "work", want: []string{filepath.Join(work, "api")}, editor: "code --wait"},
+	}
+	for _, tt := range tests {
+		var saves sync.WaitGroup
+		l, err := listing(context.Background(), configs[tt.profile], &saves).Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := lo.Map(l.Scan.Report().Projects, func(p projects.Project, _ int) string { return p.Path })
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("expected profile %q to list %v, got %v", tt.profile, tt.want, got)
+		}
+		// The scan is cached in the background, which has to be over before
+		// the cache directory is removed.
+		saves.Wait()
+		if editor, _ := terminal.Editor(terminalOptions(configs[tt.profile])); editor != tt.editor {
+			t.Errorf("expected profile %q to open %q, got %q", tt.profile, tt.editor, editor)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/samber/mo"
+
+	"dev/internal/daemon"
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+)
+
+// Daemon keeps the projects cfg would list indexed until interrupted, so
+// that dev started with the same search paths and options shows them
+// without scanning.
+func Daemon(cfg Config) mo.Result[string] {
+	fs, ok := cfg.Fs.(filesystem.WatchFileSystem)
+	if !ok {
+		return mo.Err[string](filesystem.ErrWatchUnsupported)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Branches and activity change without the directories changing, so
+	// clients read them when they list the projects instead.
+	opts := scanOptions(cfg)
+	opts.ReadGitInfo = false
+	opts.ReadActivity = false
+
+	scan := func(ctx context.Context, fs filesystem.FileSystem) mo.Result[[]projects.Project] {
+		if cfg.Flags.ScanTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Flags.ScanTimeout)
+			defer cancel()
+		}
+		return projects.Discover(ctx, fs, cfg.Paths, opts)
+	}
+
+	if err := daemon.Serve(ctx, fs, cacheKey(cfg), scan, os.Stderr); err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok("")
+}
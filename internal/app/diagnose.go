@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/mo"
+
+	"dev/internal/projects"
+)
+
+// diagnose runs a scan without the TUI or the cache and describes what it
+// found, which paths failed and which directories it left out.
+func diagnose(cfg Config) mo.Result[string] {
+	ctx, cancel := scanContext(context.Background(), cfg.Flags.ScanTimeout)
+	defer cancel()
+
+	opts := scanOptions(cfg)
+	opts.RecordSkipped = true
+
+	scan, err := projects.Stream(ctx, cfg.Fs, cfg.Paths, opts).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+
+	return mo.Ok(formatReport(scan.Report()))
+}
+
+func formatReport(r projects.DiscoverReport) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Search paths (%s total)\n", roundDuration(r.Duration))
+	for _, root := range r.Roots {
+		if len(root.TimedOut) == 0 {
+			fmt.Fprintf(w, "  %s\t%s\n", root.Path, roundDuration(root.Duration))
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\ttimed out\n", root.Path, roundDuration(root.Duration))
+		for _, dir := range root.TimedOut {
+			if dir != root.Path {
+				fmt.Fprintf(w, "    %s\t\tnot finished\n", dir)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nProjects (%d)\n", len(r.Projects))
+	for _, p := range r.Projects {
+		fmt.Fprintf(w, "  %s\t%s\n", p.Path, p.VCS)
+	}
+
+	fmt.Fprintf(w, "\nErrors (%d)\n", len(r.Errors))
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "  %s\t%s\n", e.Path, describeError(e.Err))
+	}
+
+	fmt.Fprintf(w, "\nSkipped (%d)\n", len(r.Skipped))
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "  %s\t%s\n", s.Path, s.Reason)
+	}
+
+	_ = w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// describeError leaves out the path an os error repeats, since the report
+// already shows it.
+func describeError(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Op + ": " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+
+	"dev/internal/projects"
+	"dev/internal/registry"
+)
+
+// Add registers the directory given as argument as a project, so it is
+// listed without being below a search path.
+func Add(cfg Config) mo.Result[string] {
+	return updateRegistry(cfg, "add", false, func(r *registry.Registry, path string) string {
+		return lo.Ternary(r.Add(path), "added %s", "%s is already added")
+	})
+}
+
+// Pin registers the directory given as argument, or the current directory,
+// as a project that is listed first.
+func Pin(cfg Config) mo.Result[string] {
+	return updateRegistry(cfg, "pin", true, func(r *registry.Registry, path string) string {
+		return lo.Ternary(r.Pin(path), "pinned %s", "%s is already pinned")
+	})
+}
+
+// Remove unregisters the directory given as argument. Projects below a
+// search path are still found there.
+func Remove(cfg Config) mo.Result[string] {
+	return updateRegistry(cfg, "remove", false, func(r *registry.Registry, path string) string {
+		return lo.Ternary(r.Remove(path), "removed %s", "%s is not added")
+	})
+}
+
+// Hide hides the project at the directory given as argument from the list.
+func Hide(cfg Config) mo.Result[string] {
+	return updateRegistry(cfg, "hide", false, func(r *registry.Registry, path string) string {
+		return lo.Ternary(r.Hide(path), "hid %s", "%s is already hidden")
+	})
+}
+
+// setHidden hides or shows the project at path again, as the picker asks.
+func setHidden(path string, hidden bool) error {
+	r, err := registry.Load().Get()
+	if err != nil {
+		return err
+	}
+	if hidden {
+		r.Hide(path)
+	} else {
+		r.Unhide(path)
+	}
+	return r.Save()
+}
+
+// updateRegistry applies update to the directory given as the only argument
+// and saves the registry. update returns the message to report, with a verb
+// for the path.
+func updateRegistry(cfg Config, command string, orCurrent bool, update func(*registry.Registry, string) string) mo.Result[string] {
+	arg, err := commandPath(cfg, command, orCurrent).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+
+	path, err := filepath.Abs(arg)
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	if command != "remove" {
+		info, err := cfg.Fs.Stat(path).Get()
+		if err != nil {
+			return mo.Err[string](err)
+		}
+		if !info.IsDir() {
+			return mo.Err[string](fmt.Errorf("%s is not a directory", path))
+		}
+	}
+
+	r, err := registry.Load().Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	message := fmt.Sprintf(update(&r, path), path)
+	if err := r.Save(); err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(message)
+}
+
+func commandPath(cfg Config, command string, orCurrent bool) mo.Result[string] {
+	switch {
+	case len(cfg.Args) == 1:
+		return mo.Ok(cfg.Args[0])
+	case len(cfg.Args) == 0 && orCurrent:
+		return mo.Ok(".")
+	case orCurrent:
+		return mo.Err[string](fmt.Errorf("usage: dev %s [path]", command))
+	}
+	return mo.Err[string](fmt.Errorf("usage: dev %s <path>", command))
+}
+
+// registered returns the projects registered in r that still exist.
+func registered(cfg Config, r registry.Registry) []projects.Project {
+	return lo.FilterMap(r.Entries, func(e registry.Entry, _ int) (projects.Project, bool) {
+		p, err := projects.Inspect(cfg.Fs, e.Path).Get()
+		if err != nil {
+			return p, false
+		}
+		p.Pinned = e.Pinned
+		return p, true
+	})
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dev/internal/filesystem"
+	"dev/internal/registry"
+	"dev/internal/tui"
+)
+
+func useStateDir(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+}
+
+// configure configures args the way main does, without a config file.
+func configure(t *testing.T, args ...string) Config {
+	t.Helper()
+	return Configure(Config{Args: args, Keys: tui.DefaultKeyMap(), Fs: &filesystem.RealFileSystem{}}).MustGet()
+}
+
+func TestPin_CurrentDirectoryDespiteSearchPaths(t *testing.T) {
+	useStateDir(t)
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for _, paths := range []string{"/src", "/src /srv"} {
+		t.Run(paths, func(t *testing.T) {
+			t.Setenv("DEV_PATHS", paths)
+			if _, err := Pin(configure(t)).Get(); err != nil {
+				t.Fatal(err)
+			}
+			r := registry.Load().MustGet()
+			if want := []registry.Entry{{Path: dir, Pinned: true}}; !slices.Equal(r.Entries, want) {
+				t.Errorf("expected %v to be registered, got %v", want, r.Entries)
+			}
+		})
+	}
+}
+
+func TestUpdateRegistry_UsesOnlyTheCommandArgument(t *testing.T) {
+	useStateDir(t)
+	t.Setenv("DEV_PATHS", "/src /srv")
+	dir := t.TempDir()
+
+	if _, err := Add(configure(t)).Get(); err == nil {
+		t.Error("expected add without a path to fail")
+	}
+	if _, err := Add(configure(t, dir, dir)).Get(); err == nil {
+		t.Error("expected add with two paths to fail")
+	}
+
+	if _, err := Add(configure(t, dir)).Get(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Hide(configure(t, dir)).Get(); err != nil {
+		t.Fatal(err)
+	}
+	r := registry.Load().MustGet()
+	if want := []registry.Entry{{Path: dir}}; !slices.Equal(r.Entries, want) {
+		t.Errorf("expected %v to be registered, got %v", want, r.Entries)
+	}
+	if want := []string{dir}; !slices.Equal(r.Hidden, want) {
+		t.Errorf("expected %v to be hidden, got %v", want, r.Hidden)
+	}
+
+	if _, err := Remove(configure(t, dir)).Get(); err != nil {
+		t.Fatal(err)
+	}
+	if r := registry.Load().MustGet(); len(r.Entries) != 0 {
+		t.Errorf("expected nothing registered, got %v", r.Entries)
+	}
+}
+
+func TestUpdateRegistry_RejectsFiles(t *testing.T) {
+	useStateDir(t)
+	file := filepath.Join(t.TempDir(), "README")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Pin(configure(t, file)).Get(); err == nil {
+		t.Error("expected pinning a file to fail")
+	}
+}
+
+func TestSetHidden_PersistsThroughRegistry(t *testing.T) {
+	useStateDir(t)
+	dir := t.TempDir()
+
+	if _, err := Hide(configure(t, dir)).Get(); err != nil {
+		t.Fatal(err)
+	}
+	if err := setHidden("/src/old", true); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{dir, "/src/old"}; !slices.Equal(registry.Load().MustGet().Hidden, want) {
+		t.Errorf("expected %v hidden, got %v", want, registry.Load().MustGet().Hidden)
+	}
+
+	if err := setHidden(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/src/old"}; !slices.Equal(registry.Load().MustGet().Hidden, want) {
+		t.Errorf("expected %v hidden, got %v", want, registry.Load().MustGet().Hidden)
+	}
+}
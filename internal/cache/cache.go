@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"dev/internal/projects"
+	"dev/internal/statefile"
+	"dev/internal/xdg"
+
+	"github.com/samber/mo"
+)
+
+type entry struct {
+	Key      string             `json:"key"`
+	Projects []projects.Project `json:"projects"`
+}
+
+// Load returns the projects last saved under key. Missing, outdated and
+// unreadable caches are all reported as errors so callers can fall back to
+// a full scan.
+func Load(key string) mo.Result[[]projects.Project] {
+	f := fileFor(key)
+	loaded, err := f.Load().Get()
+	if err != nil {
+		return mo.Err[[]projects.Project](fmt.Errorf("cache: %w", err))
+	}
+
+	e, ok := loaded.Get()
+	if !ok || e.Key != key {
+		return mo.Err[[]projects.Project](fmt.Errorf("cache: no entry for %s", f.Name))
+	}
+	return mo.Ok(e.Projects)
+}
+
+// Save replaces the projects stored under key.
+func Save(key string, p []projects.Project) error {
+	return fileFor(key).Save(entry{Key: key, Projects: p})
+}
+
+// Update saves what scan finds under key once it completes, together with
+// those of previous below directories it did not finish, whether it timed
+// out or was cancelled.
+func Update(key string, scan *projects.Scan, previous []projects.Project) error {
+	found := scan.Merge(previous)
+	if len(found) == 0 {
+		return nil
+	}
+	return Save(key, found)
+}
+
+func fileFor(key string) statefile.File[entry] {
+	sum := sha256.Sum256([]byte(key))
+	return statefile.File[entry]{
+		Dir:     xdg.CacheHome,
+		Name:    fmt.Sprintf("projects-%s.json", hex.EncodeToString(sum[:8])),
+		Version: 1,
+	}
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+
+	"github.com/samber/mo"
+)
+
+func useCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func paths(list []projects.Project) []string {
+	result := make([]string, len(list))
+	for i, p := range list {
+		result[i] = p.Path
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestSaveThenLoad(t *testing.T) {
+	useCacheDir(t)
+	saved := []projects.Project{
+		{Name: "api", Path: "/src/api", Head: projects.Head{Branch: "main"}},
+		{Name: "web", Path: "/src/web", Parent: "/src"},
+	}
+	if err := Save("key", saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := Load("key").Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.EqualFunc(got, saved, func(a, b projects.Project) bool {
+		return a.Path == b.Path && a.Name == b.Name && a.Parent == b.Parent && a.Head == b.Head
+	}) {
+		t.Errorf("expected %v, got %v", saved, got)
+	}
+
+	if Load("other key").IsOk() {
+		t.Error("expected nothing cached under another key")
+	}
+}
+
+func TestLoad_RejectsUnusableFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "other version", content: `{"version": 2, "key": "key", "projects": [{"name": "api", "path": "/src/api"}]}`},
+		{name: "other key", content: `{"version": 1, "key": "collision", "projects": [{"name": "api", "path": "/src/api"}]}`},
+		{name: "corrupt", content: `{"version": 1, "key": "key", "projects": [`},
+	}
+
+	for _, tt := range tests {
+		useCacheDir(t)
+		path := fileFor("key").Path().MustGet()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := Load("key"); got.IsOk() {
+			t.Errorf("%s: expected a miss, got %v", tt.name, got.MustGet())
+		}
+	}
+}
+
+func TestLoad_MissingIsAMiss(t *testing.T) {
+	useCacheDir(t)
+	if Load("key").IsOk() {
+		t.Error("expected a miss without a cache file")
+	}
+}
+
+// hangingFileSystem reads a real directory, except that reading hang
+// blocks like a stuck network mount.
+type hangingFileSystem struct {
+	filesystem.RealFileSystem
+	hang    string
+	release chan struct{}
+}
+
+func (fs *hangingFileSystem) ReadDir(path string) mo.Result[[]os.DirEntry] {
+	if path == fs.hang {
+		<-fs.release
+	}
+	return fs.RealFileSystem.ReadDir(path)
+}
+
+// scanWithStuckDir starts a scan of a search path holding the git project
+// fast and the directory nfs, which never finishes reading.
+func scanWithStuckDir(t *testing.T, ctx context.Context) (string, *projects.Scan) {
+	root := t.TempDir()
+	for _, dir := range []string{"fast/.git", "nfs/remote/.git"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	fs := &hangingFileSystem{hang: filepath.Join(root, "nfs"), release: release}
+
+	scan, err := projects.Stream(ctx, fs, []string{root}, projects.Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return root, scan
+}
+
+func TestUpdate_MergesTimedOutScan(t *testing.T) {
+	useCacheDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	root, scan := scanWithStuckDir(t, ctx)
+
+	previous := []projects.Project{
+		{Name: "remote", Path: filepath.Join(root, "nfs", "remote")},
+		{Name: "gone", Path: filepath.Join(root, "gone")},
+	}
+	if err := Update("key", scan, previous); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{filepath.Join(root, "fast"), filepath.Join(root, "nfs", "remote")}
+	if got := paths(Load("key").OrEmpty()); !slices.Equal(got, expected) {
+		t.Errorf("expected %v cached, got %v", expected, got)
+	}
+}
+
+func TestUpdate_MergesCancelledScan(t *testing.T) {
+	useCacheDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	root, scan := scanWithStuckDir(t, ctx)
+
+	previous := []projects.Project{
+		{Name: "remote", Path: filepath.Join(root, "nfs", "remote")},
+		{Name: "gone", Path: filepath.Join(root, "gone")},
+	}
+	if err := Save("key", previous); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancelled mid-walk, like a project picked before the scan ends.
+	waitForProjects(t, scan, 1)
+	cancel()
+
+	if err := Update("key", scan, Load("key").OrEmpty()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{filepath.Join(root, "fast"), filepath.Join(root, "nfs", "remote")}
+	if got := paths(Load("key").OrEmpty()); !slices.Equal(got, expected) {
+		t.Errorf("expected %v cached, got %v", expected, got)
+	}
+}
+
+func TestUpdate_CancelledBeforeAnythingKeepsPrevious(t *testing.T) {
+	useCacheDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	root, scan := scanWithStuckDir(t, ctx)
+
+	previous := []projects.Project{{Name: "fast", Path: filepath.Join(root, "fast")}}
+	if err := Update("key", scan, previous); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := paths(Load("key").OrEmpty()); !slices.Equal(got, paths(previous)) {
+		t.Errorf("expected %v cached, got %v", paths(previous), got)
+	}
+}
+
+// waitForProjects blocks until scan has found n projects.
+func waitForProjects(t *testing.T, scan *projects.Scan, n int) {
+	t.Helper()
+	for from := 0; from < n; {
+		found, done := scan.Next(from)
+		from += len(found)
+		if done && from < n {
+			t.Fatalf("expected %d projects, the scan ended with %d", n, from)
+		}
+	}
+}
+
+func TestUpdate_CompletedScanReplacesCache(t *testing.T) {
+	useCacheDir(t)
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "api", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save("key", []projects.Project{{Name: "old", Path: "/src/old"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	scan, err := projects.Stream(context.Background(), &filesystem.RealFileSystem{}, []string{root}, projects.Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Update("key", scan, Load("key").OrEmpty()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := paths(Load("key").OrEmpty()); !slices.Equal(got, []string{filepath.Join(root, "api")}) {
+		t.Errorf("expected only what the scan found, got %v", got)
+	}
+}
@@ -0,0 +1,361 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dev/internal/xdg"
+
+	"github.com/BurntSushi/toml"
+	"github.com/samber/mo"
+)
+
+// Search is what decides which projects are found. Unset fields fall back
+// to the environment and then to the defaults.
+type Search struct {
+	Paths      []string
+	MaxDepth   mo.Option[int]
+	Excludes   []string
+	Markers    []string
+	HiddenDirs []string
+}
+
+// Terminal is how the editor is opened inside one terminal multiplexer.
+type Terminal struct {
+	Launch    string
+	RenameTab mo.Option[bool]
+}
+
+// Profile narrows dev to one context, such as work. What it sets replaces
+// the top-level settings.
+type Profile struct {
+	Search
+	Editor string
+}
+
+// File is what config.toml sets.
+type File struct {
+	// Path is where the file was read from, or would be.
+	Path string
+	Search
+	// Editor is the command run when neither $EDITOR nor $VISUAL is set.
+	Editor string
+	// Keys, Theme and Icons replace the defaults by name.
+	Keys  map[string][]string
+	Theme map[string]string
+	Icons map[string]string
+	// Terminals are keyed by multiplexer name, such as "tmux".
+	Terminals map[string]Terminal
+	// Profiles are keyed by name.
+	Profiles map[string]Profile
+}
+
+// Path returns where config.toml is read from.
+func Path() mo.Result[string] {
+	dir, err := xdg.ConfigHome().Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(filepath.Join(dir, "config.toml"))
+}
+
+// Load reads config.toml. A missing file sets nothing, while one that
+// cannot be parsed is an error, so that mistakes in it are not silently
+// ignored.
+func Load() mo.Result[File] {
+	path, err := Path().Get()
+	if err != nil {
+		return mo.Err[File](err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return mo.Ok(File{Path: path})
+	}
+	if err != nil {
+		return mo.Err[File](err)
+	}
+
+	f, err := Parse(string(data)).Get()
+	if err != nil {
+		return mo.Err[File](fmt.Errorf("%s: %w", path, err))
+	}
+	f.Path = path
+	return mo.Ok(f)
+}
+
+// Parse reads the contents of a config.toml. Errors start with the line
+// they are about.
+func Parse(data string) mo.Result[File] {
+	var doc map[string]toml.Primitive
+	md, err := toml.Decode(data, &doc)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return mo.Err[File](fmt.Errorf("line %d: %s", pe.Position.Line, pe.Message))
+		}
+		return mo.Err[File](err)
+	}
+
+	f, err := decodeFile(md, doc)
+	if err != nil {
+		var le lineError
+		if errors.As(err, &le) {
+			err = fmt.Errorf("line %d: %w", le.line, err)
+		}
+		return mo.Err[File](err)
+	}
+	return mo.Ok(f)
+}
+
+func decodeFile(md toml.MetaData, doc map[string]toml.Primitive) (File, error) {
+	var f File
+	for _, key := range slices.Sorted(maps.Keys(doc)) {
+		value := doc[key]
+		ok, err := decodeSearch(md, &f.Search, key, value)
+		if !ok && err == nil {
+			switch key {
+			case "editor":
+				f.Editor, err = decode(md, value, stringOf)
+			case "keys":
+				f.Keys, err = tableOf(md, value, valueOf(stringsOf))
+			case "theme":
+				f.Theme, err = tableOf(md, value, valueOf(stringOf))
+			case "icons":
+				f.Icons, err = tableOf(md, value, valueOf(stringOf))
+			case "terminal":
+				f.Terminals, err = tableOf(md, value, terminalOf)
+			case "profiles":
+				f.Profiles, err = tableOf(md, value, profileOf)
+			default:
+				err = failAt(md, value, errors.New("unknown setting"))
+			}
+		}
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return f, nil
+}
+
+// decodeSearch sets the search setting key of s. It reports false for keys
+// that are not search settings.
+func decodeSearch(md toml.MetaData, s *Search, key string, value toml.Primitive) (bool, error) {
+	var err error
+	switch key {
+	case "paths":
+		s.Paths, err = decode(md, value, stringsOf)
+		s.Paths = expandHome(s.Paths)
+	case "max_depth":
+		var depth int
+		depth, err = decode(md, value, func(v any) (int, error) {
+			depth, err := intOf(v)
+			if err == nil && depth < 0 {
+				err = errors.New("must not be negative")
+			}
+			return depth, err
+		})
+		s.MaxDepth = mo.Some(depth)
+	case "excludes":
+		s.Excludes, err = decode(md, value, stringsOf)
+	case "markers":
+		s.Markers, err = decode(md, value, stringsOf)
+	case "hidden_dirs":
+		s.HiddenDirs, err = decode(md, value, stringsOf)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func profileOf(md toml.MetaData, value toml.Primitive) (Profile, error) {
+	var p Profile
+	table, err := tableOf(md, value, primitiveOf)
+	if err != nil {
+		return p, err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		ok, err := decodeSearch(md, &p.Search, key, table[key])
+		if !ok && err == nil {
+			if key == "editor" {
+				p.Editor, err = decode(md, table[key], stringOf)
+			} else {
+				err = failAt(md, table[key], errors.New("unknown setting"))
+			}
+		}
+		if err != nil {
+			return p, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return p, nil
+}
+
+func terminalOf(md toml.MetaData, value toml.Primitive) (Terminal, error) {
+	var t Terminal
+	table, err := tableOf(md, value, primitiveOf)
+	if err != nil {
+		return t, err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		switch key {
+		case "launch":
+			t.Launch, err = decode(md, table[key], stringOf)
+		case "rename_tab":
+			var rename bool
+			rename, err = decode(md, table[key], boolOf)
+			t.RenameTab = mo.Some(rename)
+		default:
+			err = failAt(md, table[key], errors.New("unknown setting"))
+		}
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return t, nil
+}
+
+func tableOf[T any](md toml.MetaData, value toml.Primitive, item func(toml.MetaData, toml.Primitive) (T, error)) (map[string]T, error) {
+	_, err := decode(md, value, func(v any) (struct{}, error) {
+		if _, ok := v.(map[string]any); !ok {
+			return struct{}{}, errors.New("must be a table")
+		}
+		return struct{}{}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var table map[string]toml.Primitive
+	if err := md.PrimitiveDecode(value, &table); err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]T, len(table))
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		v, err := item(md, table[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		items[key] = v
+	}
+	return items, nil
+}
+
+// primitiveOf leaves a value of a table to be decoded later.
+func primitiveOf(_ toml.MetaData, value toml.Primitive) (toml.Primitive, error) {
+	return value, nil
+}
+
+// lineError is an error about the setting on line. The line is left out of
+// its message, so that Parse can put it in front of the setting's name.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e lineError) Error() string { return e.err.Error() }
+
+func (e lineError) Unwrap() error { return e.err }
+
+// unmarshaler decodes a TOML value by calling itself with it.
+type unmarshaler func(value any) error
+
+func (u unmarshaler) UnmarshalTOML(value any) error { return u(value) }
+
+// decode reads value with read. Errors are lineErrors, since decoding value
+// through md tells the line of its key.
+func decode[T any](md toml.MetaData, value toml.Primitive, read func(any) (T, error)) (T, error) {
+	var result T
+	var readErr error
+	err := md.PrimitiveDecode(value, unmarshaler(func(v any) error {
+		result, readErr = read(v)
+		return readErr
+	}))
+	var pe toml.ParseError
+	if readErr != nil && errors.As(err, &pe) {
+		return result, lineError{line: pe.Position.Line, err: readErr}
+	}
+	return result, err
+}
+
+// valueOf turns read into an item of tableOf.
+func valueOf[T any](read func(any) (T, error)) func(toml.MetaData, toml.Primitive) (T, error) {
+	return func(md toml.MetaData, value toml.Primitive) (T, error) {
+		return decode(md, value, read)
+	}
+}
+
+// failAt returns err as an error on the line of value.
+func failAt(md toml.MetaData, value toml.Primitive, err error) error {
+	_, err = decode(md, value, func(any) (struct{}, error) { return struct{}{}, err })
+	return err
+}
+
+func stringOf(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New("must be a string")
+	}
+	return s, nil
+}
+
+// stringsOf reads a list of strings, or a single string as a list of one.
+func stringsOf(value any) ([]string, error) {
+	if s, ok := value.(string); ok {
+		return []string{s}, nil
+	}
+
+	list, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("must be a list of strings")
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be a list of strings")
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+func intOf(value any) (int, error) {
+	n, ok := value.(int64)
+	if !ok {
+		return 0, errors.New("must be a whole number")
+	}
+	return int(n), nil
+}
+
+func boolOf(value any) (bool, error) {
+	b, ok := value.(bool)
+	if !ok {
+		return false, errors.New("must be true or false")
+	}
+	return b, nil
+}
+
+// expandHome expands a leading ~ in paths, which the shell does for
+// arguments and environment variables but nobody does for this file.
+func expandHome(paths []string) []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return paths
+	}
+
+	expanded := make([]string, len(paths))
+	for i, p := range paths {
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			p = filepath.Join(home, p[1:])
+		}
+		expanded[i] = p
+	}
+	return expanded
+}
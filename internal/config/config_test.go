@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samber/mo"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("HOME", "/home/dev")
+
+	tests := []struct {
+		name string
+		src  string
+		want File
+	}{
+		{
+			name: "empty",
+			src:  "",
+			want: File{},
+		},
+		{
+			name: "search settings",
+			src: `paths = ["~/src", "/srv", "~"]
+max_depth = 3
+excludes = ["node_modules"]
+markers = "go.mod"
+hidden_dirs = [".config"]
+editor = "nvim"
+`,
+			want: File{
+				Search: Search{
+					Paths:      []string{"/home/dev/src", "/srv", "/home/dev"},
+					MaxDepth:   mo.Some(3),
+					Excludes:   []string{"node_modules"},
+					Markers:    []string{"go.mod"},
+					HiddenDirs: []string{".config"},
+				},
+				Editor: "nvim",
+			},
+		},
+		{
+			name: "keys, theme and icons",
+			src: `[keys]
+up = ["ctrl+k", "up"]
+down = "ctrl+j"
+
+[theme]
+selected = "#ff0000"
+
+[icons]
+pin = "*"
+`,
+			want: File{
+				Keys:  map[string][]string{"up": {"ctrl+k", "up"}, "down": {"ctrl+j"}},
+				Theme: map[string]string{"selected": "#ff0000"},
+				Icons: map[string]string{"pin": "*"},
+			},
+		},
+		{
+			name: "terminals",
+			src: `[terminal.tmux]
+launch = "window"
+rename_tab = false
+
+[terminal.zellij]
+launch = "pane"
+`,
+			want: File{
+				Terminals: map[string]Terminal{
+					"tmux":   {Launch: "window", RenameTab: mo.Some(false)},
+					"zellij": {Launch: "pane"},
+				},
+			},
+		},
+		{
+			name: "profiles",
+			src: `paths = ["/srv"]
+
+[profiles.work]
+paths = ["~/work"]
+excludes = ["vendor"]
+max_depth = 0
+editor = "code"
+
+[profiles.home]
+`,
+			want: File{
+				Search: Search{Paths: []string{"/srv"}},
+				Profiles: map[string]Profile{
+					"work": {
+						Search: Search{Paths: []string{"/home/dev/work"}, Excludes: []string{"vendor"}, MaxDepth: mo.Some(0)},
+						Editor: "code",
+					},
+					"home": {},
+				},
+			},
+		},
+		{
+			name: "dotted keys, inline tables and multi-line strings",
+			src: `terminal.tmux.launch = "split"
+keys = { up = "ctrl+k", "ctrl+j" = ["down"] }
+editor = """
+code --wait"""
+markers = '''go.mod'''
+
+[profiles]
+work = { paths = ["~/work"], max_depth = 2 }
+home.editor = "vim"
+`,
+			want: File{
+				Search:    Search{Markers: []string{"go.mod"}},
+				Editor:    "code --wait",
+				Keys:      map[string][]string{"up": {"ctrl+k"}, "ctrl+j": {"down"}},
+				Terminals: map[string]Terminal{"tmux": {Launch: "split"}},
+				Profiles: map[string]Profile{
+					"work": {Search: Search{Paths: []string{"/home/dev/work"}, MaxDepth: mo.Some(2)}},
+					"home": {Editor: "vim"},
+				},
+			},
+		},
+		{
+			name: "comments, literal strings and CRLF line endings",
+			src:  "# settings\r\n[theme] # colors\r\naccent = 'C:\\x' # trailing\r\n",
+			want: File{Theme: map[string]string{"accent": `C:\x`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.src).Get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "invalid TOML", src: "paths = [\n", want: "line 1: unexpected EOF; expected value"},
+		{name: "duplicate key", src: "editor = 'vim'\n\neditor = 'nano'\n", want: "line 3: Key 'editor' has already been defined."},
+		{name: "newline in string", src: "editor = 'vim\nmax_depth = 1\n", want: "line 1: strings cannot contain newlines"},
+		{name: "unknown setting", src: "path = '/srv'", want: "line 1: path: unknown setting"},
+		{name: "paths not strings", src: "editor = 'vim'\npaths = [\n'~/src',\n1,\n]", want: "line 2: paths: must be a list of strings"},
+		{name: "max_depth not a number", src: "max_depth = '3'", want: "line 1: max_depth: must be a whole number"},
+		{name: "max_depth a float", src: "max_depth = 1.5", want: "line 1: max_depth: must be a whole number"},
+		{name: "max_depth a date", src: "max_depth = 2024-01-02", want: "line 1: max_depth: must be a whole number"},
+		{name: "negative max_depth", src: "max_depth = -1", want: "line 1: max_depth: must not be negative"},
+		{name: "editor not a string", src: "editor = true", want: "line 1: editor: must be a string"},
+		{name: "keys not a table", src: "\nkeys = 'up'", want: "line 2: keys: must be a table"},
+		{name: "key binding not strings", src: "[keys]\nup = 1", want: "line 2: keys: up: must be a list of strings"},
+		{name: "unknown terminal setting", src: "[terminal.tmux]\nsplit = true", want: "line 2: terminal: tmux: split: unknown setting"},
+		{name: "unknown dotted terminal setting", src: "\n\nterminal.tmux.split = true", want: "line 3: terminal: tmux: split: unknown setting"},
+		{name: "rename_tab not a boolean", src: "[terminal.tmux]\nrename_tab = 'no'", want: "line 2: terminal: tmux: rename_tab: must be true or false"},
+		{name: "profile not a table", src: "[profiles]\nwork = '/srv'", want: "line 2: profiles: work: must be a table"},
+		{name: "inline profile not a table", src: "\nprofiles = { work = '/srv' }", want: "line 2: profiles: work: must be a table"},
+		{name: "unknown profile setting", src: "[profiles.work]\nkeys = []", want: "line 2: profiles: work: keys: unknown setting"},
+		{name: "invalid profile setting", src: "paths = []\n[profiles.work]\nmax_depth = -2", want: "line 3: profiles: work: max_depth: must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src).Get()
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "dev", "config.toml")
+
+	t.Run("missing file sets nothing", func(t *testing.T) {
+		f, err := Load().Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(f, File{Path: path}) {
+			t.Errorf("expected only the path to be set, got %+v", f)
+		}
+	})
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("file is parsed", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("editor = 'vim'\r\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Load().Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Path != path || f.Editor != "vim" {
+			t.Errorf("expected the editor from %s, got %+v", path, f)
+		}
+	})
+
+	t.Run("invalid file is an error naming it", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("editor = 'vim'\nmax_depth = 1.5\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load().Get()
+		if want := path + ": line 2: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("expected an error starting with %q, got %v", want, err)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"dev/internal/projects"
+
+	"github.com/samber/mo"
+)
+
+// queryTimeout bounds how long dev waits for a daemon before it scans by
+// itself instead.
+const queryTimeout = time.Second
+
+// Query asks the daemon for key for the projects it indexed. It fails when
+// no daemon is running for key or it has not finished its first scan.
+func Query(key string) mo.Result[[]projects.Project] {
+	return query(SocketPath(key), key)
+}
+
+func query(socket, key string) mo.Result[[]projects.Project] {
+	conn, err := net.DialTimeout("unix", socket, queryTimeout)
+	if err != nil {
+		return mo.Err[[]projects.Project](err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request{Key: key}); err != nil {
+		return mo.Err[[]projects.Project](err)
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return mo.Err[[]projects.Project](err)
+	}
+	if resp.Error != "" {
+		return mo.Err[[]projects.Project](errors.New(resp.Error))
+	}
+	return mo.Ok(resp.Projects)
+}
@@ -0,0 +1,245 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+	"dev/internal/xdg"
+
+	"github.com/samber/mo"
+)
+
+// settle is how long changes have to stop before the index is rebuilt, so a
+// checkout touching many directories costs one scan instead of hundreds.
+const settle = 500 * time.Millisecond
+
+// connTimeout bounds how long a client may take to ask and read an answer.
+const connTimeout = 5 * time.Second
+
+// Scanner finds projects, reading directories through fs.
+type Scanner func(ctx context.Context, fs filesystem.FileSystem) mo.Result[[]projects.Project]
+
+type request struct {
+	Key string `json:"key"`
+}
+
+type response struct {
+	Projects []projects.Project `json:"projects"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// SocketPath returns where the daemon for key listens. Every configuration
+// has its own socket, so daemons for different search paths can run side by
+// side and dev only ever talks to the one that matches it.
+func SocketPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := fmt.Sprintf("daemon-%s.sock", hex.EncodeToString(sum[:8]))
+	return filepath.Join(xdg.RuntimeDir(), name)
+}
+
+// Serve keeps an index of what scan finds and hands it to clients asking
+// for key on SocketPath(key) until ctx ends. The index is rebuilt whenever
+// an entry is created, removed or renamed in a directory the last scan
+// read. A scan that fails leaves the index as it was until the next change.
+// Progress and scan errors are written to log.
+func Serve(ctx context.Context, fs filesystem.WatchFileSystem, key string, scan Scanner, log io.Writer) error {
+	watcher, err := fs.Watch().Get()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	ln, err := listen(SocketPath(key))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ln.Close() }()
+
+	idx := &index{key: key}
+	go idx.serve(ln)
+	fmt.Fprintf(log, "listening on %s\n", ln.Addr())
+
+	watched := make(map[string]struct{})
+	for {
+		rec := &recordingFS{FileSystem: fs, dirs: make(map[string]struct{})}
+		started := time.Now()
+		list, err := scan(ctx, rec).Get()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// What was watched stays watched, so that the next change
+			// brings another try.
+			fmt.Fprintf(log, "scan failed, keeping the previous index: %v\n", err)
+			maps.Copy(rec.dirs, watched)
+		} else {
+			idx.set(list)
+		}
+
+		failed := rewatch(watcher, watched, rec.dirs)
+		if err == nil {
+			fmt.Fprintf(log, "indexed %d projects in %s, watching %d directories\n",
+				len(list), time.Since(started).Round(time.Millisecond), len(watched))
+		}
+		if failed != nil {
+			fmt.Fprintf(log, "changes in some directories will be missed: %v\n", failed)
+		}
+
+		if err := waitForChanges(ctx, watcher.Events()); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// rewatch watches dirs and stops watching what was watched before but is
+// not in dirs anymore. It returns the first directory it failed to watch,
+// typically because the inotify watch limit is reached.
+func rewatch(w filesystem.Watcher, watched, dirs map[string]struct{}) error {
+	for dir := range watched {
+		if _, ok := dirs[dir]; !ok {
+			_ = w.Remove(dir)
+			delete(watched, dir)
+		}
+	}
+
+	var failed error
+	for dir := range dirs {
+		// Add is called for directories watched already too, since the
+		// watch is gone if the directory was removed and created again.
+		if err := w.Add(dir); err != nil {
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+		watched[dir] = struct{}{}
+	}
+	return failed
+}
+
+// waitForChanges blocks until something changed and then until nothing has
+// changed for settle, or until ctx ends.
+func waitForChanges(ctx context.Context, events <-chan string) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case _, ok := <-events:
+		if !ok {
+			return errors.New("watcher stopped")
+		}
+	}
+
+	timer := time.NewTimer(settle)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case _, ok := <-events:
+			if !ok {
+				return errors.New("watcher stopped")
+			}
+			timer.Reset(settle)
+		}
+	}
+}
+
+// listen creates the socket at path, replacing one left behind by a daemon
+// that did not exit cleanly, but not one a daemon is still answering on.
+func listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	_ = os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// index is the latest list of projects, empty until the first scan
+// completes.
+type index struct {
+	key      string
+	mu       sync.RWMutex
+	projects []projects.Project
+	ready    bool
+}
+
+func (idx *index) set(list []projects.Project) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.projects = list
+	idx.ready = true
+}
+
+func (idx *index) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go idx.answer(conn)
+	}
+}
+
+func (idx *index) answer(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(connTimeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	idx.mu.RLock()
+	var resp response
+	switch {
+	case req.Key != idx.key:
+		resp.Error = "daemon indexes other search paths or options"
+	case !idx.ready:
+		resp.Error = "daemon is still indexing"
+	default:
+		resp.Projects = idx.projects
+	}
+	idx.mu.RUnlock()
+
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// recordingFS notes the directories read through it, which are the ones
+// whose entries decide what a scan finds.
+type recordingFS struct {
+	filesystem.FileSystem
+	mu   sync.Mutex
+	dirs map[string]struct{}
+}
+
+func (fs *recordingFS) ReadDir(path string) mo.Result[[]os.DirEntry] {
+	entries := fs.FileSystem.ReadDir(path)
+	if entries.IsOk() {
+		fs.mu.Lock()
+		fs.dirs[path] = struct{}{}
+		fs.mu.Unlock()
+	}
+	return entries
+}
@@ -0,0 +1,323 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dev/internal/filesystem"
+	"dev/internal/projects"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// fakeWatcher reports the events a test sends instead of real ones.
+type fakeWatcher struct {
+	mu      sync.Mutex
+	watched map[string]struct{}
+	events  chan string
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{watched: make(map[string]struct{}), events: make(chan string, 16)}
+}
+
+func (w *fakeWatcher) Add(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watched[dir] = struct{}{}
+	return nil
+}
+
+func (w *fakeWatcher) Remove(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.watched, dir)
+	return nil
+}
+
+func (w *fakeWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *fakeWatcher) Close() error {
+	return nil
+}
+
+func (w *fakeWatcher) isWatching(dir string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.watched[dir]
+	return ok
+}
+
+// fakeWatchFS reads the real file system but watches it with a fakeWatcher.
+type fakeWatchFS struct {
+	*filesystem.RealFileSystem
+	watcher *fakeWatcher
+}
+
+func (fs fakeWatchFS) Watch() mo.Result[filesystem.Watcher] {
+	return mo.Ok[filesystem.Watcher](fs.watcher)
+}
+
+func useRuntimeDir(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+}
+
+func mkdirs(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func discover(root string) Scanner {
+	return func(ctx context.Context, fs filesystem.FileSystem) mo.Result[[]projects.Project] {
+		return projects.Discover(ctx, fs, []string{root}, projects.Options{})
+	}
+}
+
+// serve runs Serve until the test ends.
+func serve(t *testing.T, fs filesystem.WatchFileSystem, key string, scan Scanner) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, fs, key, scan, &strings.Builder{}) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected Serve to stop without an error, got %v", err)
+		}
+	})
+}
+
+// waitForIndex queries the daemon for key until it lists want.
+func waitForIndex(t *testing.T, key string, want ...string) {
+	t.Helper()
+	var got []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if list, err := Query(key).Get(); err == nil {
+			got = lo.Map(list, func(p projects.Project, _ int) string { return p.Path })
+			slices.Sort(got)
+			if slices.Equal(got, want) {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the daemon to list %v, got %v", want, got)
+}
+
+func TestServe_ReindexesAfterChanges(t *testing.T) {
+	useRuntimeDir(t)
+	root := t.TempDir()
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	mkdirs(t, filepath.Join(a, ".git"))
+
+	watcher := newFakeWatcher()
+	serve(t, fakeWatchFS{&filesystem.RealFileSystem{}, watcher}, "key", discover(root))
+
+	waitForIndex(t, "key", a)
+	if !watcher.isWatching(root) {
+		t.Errorf("expected %s to be watched", root)
+	}
+
+	mkdirs(t, filepath.Join(b, ".git"))
+	watcher.events <- b
+	waitForIndex(t, "key", a, b)
+	if !watcher.isWatching(b) {
+		t.Errorf("expected the created %s to be watched", b)
+	}
+
+	if err := os.RemoveAll(a); err != nil {
+		t.Fatal(err)
+	}
+	watcher.events <- a
+	waitForIndex(t, "key", b)
+	if watcher.isWatching(a) {
+		t.Errorf("expected the removed %s not to be watched anymore", a)
+	}
+}
+
+func TestServe_AnswersOnlyOnceIndexed(t *testing.T) {
+	useRuntimeDir(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	scan := func(ctx context.Context, _ filesystem.FileSystem) mo.Result[[]projects.Project] {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return mo.Ok([]projects.Project{{Name: "a", Path: "/src/a"}})
+	}
+	fs := fakeWatchFS{&filesystem.RealFileSystem{}, newFakeWatcher()}
+	serve(t, fs, "key", scan)
+	<-started
+
+	_, err := Query("key").Get()
+	if err == nil || !strings.Contains(err.Error(), "still indexing") {
+		t.Errorf("expected a daemon still indexing to say so, got %v", err)
+	}
+
+	close(release)
+	waitForIndex(t, "key", "/src/a")
+}
+
+func TestServe_KeepsServingWhenScanFails(t *testing.T) {
+	useRuntimeDir(t)
+
+	results := make(chan mo.Result[[]projects.Project])
+	scan := func(ctx context.Context, _ filesystem.FileSystem) mo.Result[[]projects.Project] {
+		select {
+		case result := <-results:
+			return result
+		case <-ctx.Done():
+			return mo.Err[[]projects.Project](ctx.Err())
+		}
+	}
+	watcher := newFakeWatcher()
+	serve(t, fakeWatchFS{&filesystem.RealFileSystem{}, watcher}, "key", scan)
+	a := projects.Project{Name: "a", Path: "/src/a"}
+	b := projects.Project{Name: "b", Path: "/src/b"}
+
+	results <- mo.Err[[]projects.Project](errors.New("permission denied"))
+	if _, err := Query("key").Get(); err == nil || !strings.Contains(err.Error(), "still indexing") {
+		t.Errorf("expected a daemon whose first scan failed to be still indexing, got %v", err)
+	}
+
+	watcher.events <- "/src"
+	results <- mo.Ok([]projects.Project{a})
+	waitForIndex(t, "key", a.Path)
+
+	watcher.events <- "/src"
+	results <- mo.Err[[]projects.Project](errors.New("permission denied"))
+	waitForIndex(t, "key", a.Path)
+
+	// The daemon scans again on the next change rather than exiting.
+	watcher.events <- "/src"
+	results <- mo.Ok([]projects.Project{a, b})
+	waitForIndex(t, "key", a.Path, b.Path)
+}
+
+func TestServe_RefusesSecondDaemonForSameKey(t *testing.T) {
+	useRuntimeDir(t)
+	root := t.TempDir()
+	mkdirs(t, filepath.Join(root, "a", ".git"))
+
+	serve(t, fakeWatchFS{&filesystem.RealFileSystem{}, newFakeWatcher()}, "key", discover(root))
+	waitForIndex(t, "key", filepath.Join(root, "a"))
+
+	err := Serve(context.Background(), fakeWatchFS{&filesystem.RealFileSystem{}, newFakeWatcher()}, "key", discover(root), &strings.Builder{})
+	if err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("expected a second daemon for the same key to be refused, got %v", err)
+	}
+	waitForIndex(t, "key", filepath.Join(root, "a"))
+}
+
+func TestQuery_FailsWithoutMatchingDaemon(t *testing.T) {
+	useRuntimeDir(t)
+	root := t.TempDir()
+	mkdirs(t, filepath.Join(root, "a", ".git"))
+
+	if _, err := Query("key").Get(); err == nil {
+		t.Error("expected a query without a socket to fail")
+	}
+
+	serve(t, fakeWatchFS{&filesystem.RealFileSystem{}, newFakeWatcher()}, "key", discover(root))
+	waitForIndex(t, "key", filepath.Join(root, "a"))
+
+	if _, err := Query("other").Get(); err == nil {
+		t.Error("expected a query for other options not to reach the daemon")
+	}
+	_, err := query(SocketPath("key"), "other").Get()
+	if err == nil || !strings.Contains(err.Error(), "other search paths") {
+		t.Errorf("expected a daemon to refuse a query with another key, got %v", err)
+	}
+}
+
+func TestServe_ReplacesStaleSocket(t *testing.T) {
+	useRuntimeDir(t)
+	root := t.TempDir()
+	mkdirs(t, filepath.Join(root, "a", ".git"))
+
+	// A daemon that did not exit cleanly leaves its socket file behind.
+	mkdirs(t, filepath.Dir(SocketPath("key")))
+	if err := os.WriteFile(SocketPath("key"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Query("key").Get(); err == nil {
+		t.Error("expected a query on a stale socket to fail")
+	}
+
+	serve(t, fakeWatchFS{&filesystem.RealFileSystem{}, newFakeWatcher()}, "key", discover(root))
+	waitForIndex(t, "key", filepath.Join(root, "a"))
+}
+
+func TestRewatch(t *testing.T) {
+	w := newFakeWatcher()
+	watched := map[string]struct{}{}
+
+	if err := rewatch(w, watched, map[string]struct{}{"/src": {}, "/src/a": {}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rewatch(w, watched, map[string]struct{}{"/src": {}, "/src/b": {}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := lo.Keys(w.watched)
+	slices.Sort(got)
+	if want := []string{"/src", "/src/b"}; !slices.Equal(got, want) {
+		t.Errorf("expected watches on %v, got %v", want, got)
+	}
+	if len(watched) != 2 {
+		t.Errorf("expected rewatch to keep track of 2 directories, got %v", watched)
+	}
+}
+
+func TestWaitForChanges(t *testing.T) {
+	t.Run("settles after the last event", func(t *testing.T) {
+		events := make(chan string, 4)
+		events <- "/src/a"
+		events <- "/src/b"
+		started := time.Now()
+		if err := waitForChanges(context.Background(), events); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(started); elapsed < settle {
+			t.Errorf("expected to wait at least %s, waited %s", settle, elapsed)
+		}
+		if len(events) != 0 {
+			t.Errorf("expected the events to be read, %d left", len(events))
+		}
+	})
+
+	t.Run("fails when the watcher stops", func(t *testing.T) {
+		events := make(chan string)
+		close(events)
+		if err := waitForChanges(context.Background(), events); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("returns when ctx ends", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := waitForChanges(ctx, make(chan string)); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
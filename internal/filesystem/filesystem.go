@@ -2,12 +2,16 @@ package filesystem
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/samber/mo"
 )
 
 type FileSystem interface {
 	ReadDir(path string) mo.Result[[]os.DirEntry]
+	ReadFile(path string) mo.Result[[]byte]
+	Stat(path string) mo.Result[os.FileInfo]
+	EvalSymlinks(path string) mo.Result[string]
 	Chdir(path string) mo.Result[string]
 }
 
@@ -21,6 +25,30 @@ func (fs *RealFileSystem) ReadDir(path string) mo.Result[[]os.DirEntry] {
 	return mo.Ok(dirEntry)
 }
 
+func (fs *RealFileSystem) ReadFile(path string) mo.Result[[]byte] {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mo.Err[[]byte](err)
+	}
+	return mo.Ok(data)
+}
+
+func (fs *RealFileSystem) Stat(path string) mo.Result[os.FileInfo] {
+	info, err := os.Stat(path)
+	if err != nil {
+		return mo.Err[os.FileInfo](err)
+	}
+	return mo.Ok(info)
+}
+
+func (fs *RealFileSystem) EvalSymlinks(path string) mo.Result[string] {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(resolved)
+}
+
 func (fs *RealFileSystem) Chdir(path string) mo.Result[string] {
 	if err := os.Chdir(path); err != nil {
 		return mo.Err[string](err)
@@ -0,0 +1,35 @@
+package filesystem
+
+import (
+	"errors"
+
+	"github.com/samber/mo"
+)
+
+// ErrWatchUnsupported is returned by Watch on platforms without a way to
+// watch directories.
+var ErrWatchUnsupported = errors.New("watching directories is not supported on this platform")
+
+// Watcher reports entries being created, removed or renamed in the
+// directories it watches. Directories are watched one by one, not
+// recursively.
+type Watcher interface {
+	Add(dir string) error
+	Remove(dir string) error
+	// Events delivers the paths of changed entries. Changes may be merged
+	// or dropped when they come faster than they are read, and an empty
+	// path means some were lost.
+	Events() <-chan string
+	Close() error
+}
+
+// WatchFileSystem is a FileSystem that can also watch directories.
+type WatchFileSystem interface {
+	FileSystem
+	Watch() mo.Result[Watcher]
+}
+
+// Watch returns a new watcher on the real file system.
+func (fs *RealFileSystem) Watch() mo.Result[Watcher] {
+	return newWatcher()
+}
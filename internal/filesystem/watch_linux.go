@@ -0,0 +1,152 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/samber/mo"
+)
+
+// watchMask is the inotify events that change which entries a directory
+// has. Writes to files inside it are not of interest.
+const watchMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF | syscall.IN_ONLYDIR
+
+// inotifyWatcher watches directories with inotify. The file descriptor is
+// non-blocking so that reads go through the runtime poller and Close
+// interrupts them.
+type inotifyWatcher struct {
+	file   *os.File
+	fd     int
+	mu     sync.Mutex
+	dirs   map[int]string
+	wds    map[string]int
+	events chan string
+}
+
+func newWatcher() mo.Result[Watcher] {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return mo.Err[Watcher](os.NewSyscallError("inotify_init1", err))
+	}
+
+	w := &inotifyWatcher{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		dirs:   make(map[int]string),
+		wds:    make(map[string]int),
+		events: make(chan string, 256),
+	}
+	go w.read()
+	return mo.Ok[Watcher](w)
+}
+
+func (w *inotifyWatcher) Add(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.wds[dir]; ok {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, watchMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	w.dirs[wd] = dir
+	w.wds[dir] = wd
+	return nil
+}
+
+func (w *inotifyWatcher) Remove(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wd, ok := w.wds[dir]
+	if !ok {
+		return nil
+	}
+	delete(w.wds, dir)
+	delete(w.dirs, wd)
+	if _, err := syscall.InotifyRmWatch(w.fd, uint32(wd)); err != nil {
+		return &os.PathError{Op: "inotify_rm_watch", Path: dir, Err: err}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		w.parse(buf[:n])
+	}
+}
+
+// parse splits buf into inotify events, each a fixed header followed by a
+// NUL padded name.
+func (w *inotifyWatcher) parse(buf []byte) {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		wd := int(int32(binary.NativeEndian.Uint32(buf[0:4])))
+		mask := binary.NativeEndian.Uint32(buf[4:8])
+		nameLen := int(binary.NativeEndian.Uint32(buf[12:16]))
+		end := min(syscall.SizeofInotifyEvent+nameLen, len(buf))
+		name := string(trimNUL(buf[syscall.SizeofInotifyEvent:end]))
+		buf = buf[end:]
+
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			w.send("")
+			continue
+		}
+
+		w.mu.Lock()
+		dir, ok := w.dirs[wd]
+		if mask&syscall.IN_IGNORED != 0 && ok {
+			// The kernel dropped the watch, because the directory is gone
+			// or Remove was called.
+			delete(w.dirs, wd)
+			if w.wds[dir] == wd {
+				delete(w.wds, dir)
+			}
+		}
+		w.mu.Unlock()
+
+		if ok && mask&watchMask != 0 {
+			w.send(filepath.Join(dir, name))
+		}
+	}
+}
+
+// send delivers path unless the reader is behind. Dropping is fine then:
+// the events already waiting tell the reader that something changed.
+func (w *inotifyWatcher) send(path string) {
+	select {
+	case w.events <- path:
+	default:
+	}
+}
+
+func trimNUL(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
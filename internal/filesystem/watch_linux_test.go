@@ -0,0 +1,182 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// inotifyEvent encodes an event the way the kernel does, with name padded
+// to a multiple of the header size.
+func inotifyEvent(wd int32, mask uint32, name string) []byte {
+	nameLen := 0
+	if name != "" {
+		nameLen = (len(name)/syscall.SizeofInotifyEvent + 1) * syscall.SizeofInotifyEvent
+	}
+	buf := make([]byte, syscall.SizeofInotifyEvent+nameLen)
+	binary.NativeEndian.PutUint32(buf[0:4], uint32(wd))
+	binary.NativeEndian.PutUint32(buf[4:8], mask)
+	binary.NativeEndian.PutUint32(buf[12:16], uint32(nameLen))
+	copy(buf[syscall.SizeofInotifyEvent:], name)
+	return buf
+}
+
+func drain(events chan string) []string {
+	var paths []string
+	for {
+		select {
+		case path := <-events:
+			paths = append(paths, path)
+		default:
+			return paths
+		}
+	}
+}
+
+func TestInotifyWatcher_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   [][]byte
+		want     []string
+		wantDirs map[int]string
+	}{
+		{
+			name:     "created entry",
+			events:   [][]byte{inotifyEvent(1, syscall.IN_CREATE|syscall.IN_ISDIR, "api")},
+			want:     []string{"/src/api"},
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+		{
+			name: "several events in one read",
+			events: [][]byte{
+				inotifyEvent(1, syscall.IN_DELETE, "api"),
+				inotifyEvent(2, syscall.IN_MOVED_TO, "a-much-longer-name-than-one-header"),
+			},
+			want:     []string{"/src/api", "/src/web/a-much-longer-name-than-one-header"},
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+		{
+			name:     "watched directory removed",
+			events:   [][]byte{inotifyEvent(2, syscall.IN_DELETE_SELF, ""), inotifyEvent(2, syscall.IN_IGNORED, "")},
+			want:     []string{"/src/web"},
+			wantDirs: map[int]string{1: "/src"},
+		},
+		{
+			name:     "queue overflow",
+			events:   [][]byte{inotifyEvent(-1, syscall.IN_Q_OVERFLOW, "")},
+			want:     []string{""},
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+		{
+			name:     "unknown watch",
+			events:   [][]byte{inotifyEvent(7, syscall.IN_CREATE, "api")},
+			want:     nil,
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+		{
+			name:     "event not watched for",
+			events:   [][]byte{inotifyEvent(1, syscall.IN_MODIFY, "api")},
+			want:     nil,
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+		{
+			name:     "truncated event",
+			events:   [][]byte{inotifyEvent(1, syscall.IN_CREATE, "api")[:syscall.SizeofInotifyEvent+2]},
+			want:     []string{"/src/ap"},
+			wantDirs: map[int]string{1: "/src", 2: "/src/web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &inotifyWatcher{
+				dirs:   map[int]string{1: "/src", 2: "/src/web"},
+				wds:    map[string]int{"/src": 1, "/src/web": 2},
+				events: make(chan string, 8),
+			}
+			w.parse(slices.Concat(tt.events...))
+
+			if got := drain(w.events); !slices.Equal(got, tt.want) {
+				t.Errorf("expected events %q, got %q", tt.want, got)
+			}
+			if len(w.dirs) != len(tt.wantDirs) || len(w.wds) != len(tt.wantDirs) {
+				t.Errorf("expected watches %v, got %v and %v", tt.wantDirs, w.dirs, w.wds)
+			}
+			for wd, dir := range tt.wantDirs {
+				if w.dirs[wd] != dir || w.wds[dir] != wd {
+					t.Errorf("expected %s to be watched as %d, got %v and %v", dir, wd, w.dirs, w.wds)
+				}
+			}
+		})
+	}
+}
+
+func TestInotifyWatcher_ReportsCreatedAndRemovedEntries(t *testing.T) {
+	root := t.TempDir()
+	w := newWatcher().MustGet()
+	defer w.Close()
+
+	if err := w.Add(root); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, "api")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, w, dir)
+
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, w, dir)
+}
+
+func TestInotifyWatcher_AddFailsForMissingDirectory(t *testing.T) {
+	w := newWatcher().MustGet()
+	defer w.Close()
+
+	if err := w.Add(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestInotifyWatcher_CloseEndsEvents(t *testing.T) {
+	w := newWatcher().MustGet()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Error("expected no events after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Close to end the events")
+	}
+}
+
+func expectEvent(t *testing.T, w Watcher, path string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got := <-w.Events():
+			if got == path {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected an event for %s", path)
+		}
+	}
+}
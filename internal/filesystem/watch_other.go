@@ -0,0 +1,9 @@
+//go:build !linux
+
+package filesystem
+
+import "github.com/samber/mo"
+
+func newWatcher() mo.Result[Watcher] {
+	return mo.Err[Watcher](ErrWatchUnsupported)
+}
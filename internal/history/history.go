@@ -0,0 +1,62 @@
+package history
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"dev/internal/projects"
+	"dev/internal/statefile"
+	"dev/internal/xdg"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// maxEntries bounds the history. The entries with the lowest frecency are
+// forgotten first.
+const maxEntries = 1000
+
+type file struct {
+	Visits projects.Visits `json:"visits"`
+}
+
+var historyFile = statefile.File[file]{Dir: xdg.StateHome, Name: "history.json", Version: 1}
+
+// Load returns the recorded visits. A missing history is empty, not an
+// error.
+func Load() mo.Result[projects.Visits] {
+	f, err := historyFile.Load().Get()
+	if err != nil {
+		return mo.Err[projects.Visits](fmt.Errorf("history: %w", err))
+	}
+	if visits := f.OrEmpty().Visits; visits != nil {
+		return mo.Ok(visits)
+	}
+	return mo.Ok(projects.Visits{})
+}
+
+// Record adds a visit to the project at path to the history.
+func Record(path string) error {
+	visits := Load().OrElse(projects.Visits{})
+	now := time.Now()
+	visits.Record(path, now)
+	prune(visits, now)
+	return historyFile.Save(file{Visits: visits})
+}
+
+func prune(visits projects.Visits, now time.Time) {
+	if len(visits) <= maxEntries {
+		return
+	}
+
+	scores := visits.Scores(now)
+	paths := lo.Keys(visits)
+	slices.SortFunc(paths, func(a, b string) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	for _, path := range paths[maxEntries:] {
+		delete(visits, path)
+	}
+}
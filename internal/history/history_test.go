@@ -0,0 +1,104 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dev/internal/projects"
+)
+
+func useStateDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	return dir
+}
+
+func TestLoad_MissingIsEmpty(t *testing.T) {
+	useStateDir(t)
+
+	visits, err := Load().Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if visits == nil || len(visits) != 0 {
+		t.Errorf("expected an empty history, got %#v", visits)
+	}
+}
+
+func TestLoad_CorruptIsAnError(t *testing.T) {
+	dir := filepath.Join(useStateDir(t), "dev")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "history.json"), []byte(`{"version": 1, "visits": {`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if Load().IsOk() {
+		t.Error("expected an error for a corrupt history")
+	}
+}
+
+func TestRecordThenLoad(t *testing.T) {
+	useStateDir(t)
+	before := time.Now()
+
+	for _, path := range []string{"/src/api", "/src/web", "/src/api"} {
+		if err := Record(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	visits, err := Load().Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(visits) != 2 {
+		t.Fatalf("expected two projects visited, got %v", visits)
+	}
+	for path, count := range map[string]int{"/src/api": 2, "/src/web": 1} {
+		visit := visits[path]
+		if visit.Count != count {
+			t.Errorf("expected %d visits to %s, got %d", count, path, visit.Count)
+		}
+		if visit.Last.Before(before) || visit.Last.After(time.Now()) {
+			t.Errorf("expected the last visit to %s to be now, got %v", path, visit.Last)
+		}
+	}
+}
+
+func TestPrune_KeepsHighestFrecency(t *testing.T) {
+	now := time.Now()
+	visits := projects.Visits{}
+	for i := range maxEntries + 10 {
+		// Visited longer ago the higher i is, so the last ten rank lowest.
+		visits[fmt.Sprintf("/src/%d", i)] = projects.Visit{Count: 1, Last: now.Add(-time.Duration(i) * time.Hour)}
+	}
+
+	prune(visits, now)
+
+	if len(visits) != maxEntries {
+		t.Fatalf("expected %d entries kept, got %d", maxEntries, len(visits))
+	}
+	for i := range maxEntries + 10 {
+		path := fmt.Sprintf("/src/%d", i)
+		if _, kept := visits[path]; kept != (i < maxEntries) {
+			t.Errorf("expected %s kept to be %t", path, i < maxEntries)
+		}
+	}
+}
+
+func TestPrune_KeepsSmallHistory(t *testing.T) {
+	now := time.Now()
+	visits := projects.Visits{"/src/old": {Count: 1, Last: now.AddDate(-5, 0, 0)}}
+
+	prune(visits, now)
+
+	if len(visits) != 1 {
+		t.Errorf("expected a history below the limit to be kept whole, got %v", visits)
+	}
+}
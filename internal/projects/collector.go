@@ -0,0 +1,110 @@
+package projects
+
+import (
+	"context"
+	"sync"
+
+	"github.com/samber/mo"
+)
+
+// Update is what a Collector computed for the project at Path.
+type Update[T any] struct {
+	Path  string
+	Value T
+}
+
+// Collector computes a T for projects in the background on a few workers,
+// so a slow project never holds up the caller.
+type Collector[T any] struct {
+	ctx     context.Context
+	compute func(ctx context.Context, p Project) mo.Result[T]
+	wants   func(p Project) bool
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []Project
+	seen    map[string]struct{}
+	updates chan Update[T]
+}
+
+// NewCollector starts workers computing values with compute until ctx ends,
+// for the projects wants accepts.
+func NewCollector[T any](ctx context.Context, compute func(ctx context.Context, p Project) mo.Result[T], wants func(p Project) bool, workers int) *Collector[T] {
+	c := &Collector[T]{
+		ctx:     ctx,
+		compute: compute,
+		wants:   wants,
+		seen:    make(map[string]struct{}),
+		updates: make(chan Update[T], 64),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	for range max(workers, 1) {
+		go c.work()
+	}
+	return c
+}
+
+// Request queues the projects of list that have not been asked for yet.
+func (c *Collector[T]) Request(list []Project) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range list {
+		if !c.wants(p) {
+			continue
+		}
+		if _, ok := c.seen[p.Path]; ok {
+			continue
+		}
+		c.seen[p.Path] = struct{}{}
+		c.queue = append(c.queue, p)
+		c.cond.Signal()
+	}
+}
+
+// Updates delivers values as they are computed. Projects whose value fails
+// are skipped.
+func (c *Collector[T]) Updates() <-chan Update[T] {
+	return c.updates
+}
+
+func (c *Collector[T]) work() {
+	for {
+		p, ok := c.next()
+		if !ok {
+			return
+		}
+
+		value, err := c.compute(c.ctx, p).Get()
+		if err != nil {
+			continue
+		}
+
+		select {
+		case c.updates <- Update[T]{Path: p.Path, Value: value}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Collector[T]) next() (Project, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.queue) == 0 && c.ctx.Err() == nil {
+		c.cond.Wait()
+	}
+	if c.ctx.Err() != nil {
+		return Project{}, false
+	}
+
+	p := c.queue[0]
+	c.queue = c.queue[1:]
+	return p, true
+}
@@ -0,0 +1,274 @@
+package projects
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/mo"
+)
+
+type VCS string
+
+const (
+	VCSGit        VCS = "git"
+	VCSJujutsu    VCS = "jj"
+	VCSMercurial  VCS = "hg"
+	VCSSubversion VCS = "svn"
+	VCSFossil     VCS = "fossil"
+	VCSPijul      VCS = "pijul"
+)
+
+// metadataDirs are the directories version control keeps its own data in.
+// They are never walked, even when hidden directories are.
+var metadataDirs = map[string]bool{
+	".git":   true,
+	".jj":    true,
+	".hg":    true,
+	".svn":   true,
+	".pijul": true,
+}
+
+// Detector recognizes a project directory by one of its entries.
+type Detector interface {
+	// Match reports whether entry marks its parent directory as a project.
+	Match(entry os.DirEntry) bool
+	// Project builds the project for dir once Match has succeeded, or
+	// returns ErrNotProject if a closer look rules dir out after all.
+	Project(fs filesystem.FileSystem, dir string, entry os.DirEntry) mo.Result[Project]
+}
+
+// ErrNotProject is returned by Detector.Project for a directory that
+// matched but turned out not to be a project. It is not reported.
+var ErrNotProject = errors.New("not a project")
+
+// DefaultDetectors returns the built-in detectors in priority order. Jujutsu
+// comes before git so colocated repositories are reported as jj.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		MarkerDetector{VCS: VCSJujutsu, Names: []string{".jj"}},
+		GitDetector{},
+		BareGitDetector{},
+		MarkerDetector{VCS: VCSMercurial, Names: []string{".hg"}},
+		MarkerDetector{VCS: VCSSubversion, Names: []string{".svn"}},
+		MarkerDetector{VCS: VCSFossil, Names: []string{"_FOSSIL_", ".fslckout"}},
+		MarkerDetector{VCS: VCSPijul, Names: []string{".pijul"}},
+	}
+}
+
+// MarkerDetector matches any entry with one of the given names.
+type MarkerDetector struct {
+	VCS   VCS
+	Names []string
+}
+
+func (d MarkerDetector) Match(entry os.DirEntry) bool {
+	for _, name := range d.Names {
+		if entry.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d MarkerDetector) Project(_ filesystem.FileSystem, dir string, _ os.DirEntry) mo.Result[Project] {
+	return mo.Ok(Project{
+		Name: filepath.Base(dir),
+		Path: dir,
+		VCS:  d.VCS,
+	})
+}
+
+// GlobDetector matches entries against shell patterns such as "go.mod" or
+// "*.csproj", for projects that are not under version control.
+type GlobDetector struct {
+	Patterns []string
+}
+
+func (d GlobDetector) Match(entry os.DirEntry) bool {
+	for _, pattern := range d.Patterns {
+		if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (d GlobDetector) Project(_ filesystem.FileSystem, dir string, _ os.DirEntry) mo.Result[Project] {
+	return mo.Ok(Project{
+		Name: filepath.Base(dir),
+		Path: dir,
+	})
+}
+
+// GitDetector matches a ".git" directory, or a ".git" file as written by
+// `git worktree add` and submodule checkouts.
+type GitDetector struct{}
+
+func (d GitDetector) Match(entry os.DirEntry) bool {
+	return entry.Name() == ".git"
+}
+
+func (d GitDetector) Project(fs filesystem.FileSystem, dir string, entry os.DirEntry) mo.Result[Project] {
+	if entry.IsDir() {
+		return mo.Ok(Project{
+			Name: filepath.Base(dir),
+			Path: dir,
+			VCS:  VCSGit,
+		})
+	}
+	return gitFileProject(fs, dir)
+}
+
+// BareGitDetector matches a bare git repository, which has HEAD, objects
+// and refs at its top level instead of in a ".git" directory.
+type BareGitDetector struct{}
+
+func (d BareGitDetector) Match(entry os.DirEntry) bool {
+	return entry.Name() == "HEAD" && !entry.IsDir()
+}
+
+func (d BareGitDetector) Project(fs filesystem.FileSystem, dir string, _ os.DirEntry) mo.Result[Project] {
+	for _, sub := range []string{"objects", "refs"} {
+		info, err := fs.Stat(filepath.Join(dir, sub)).Get()
+		if err != nil || !info.IsDir() {
+			return mo.Err[Project](ErrNotProject)
+		}
+	}
+
+	head, err := fs.ReadFile(filepath.Join(dir, "HEAD")).Get()
+	if err != nil {
+		return mo.Err[Project](err)
+	}
+	if !isGitHead(strings.TrimSpace(string(head))) {
+		return mo.Err[Project](ErrNotProject)
+	}
+
+	return mo.Ok(Project{
+		Name: strings.TrimSuffix(filepath.Base(dir), ".git"),
+		Path: dir,
+		VCS:  VCSGit,
+		Bare: true,
+	})
+}
+
+// isGitHead reports whether head is what git writes to HEAD: a symbolic
+// ref, or the object name of a detached commit.
+func isGitHead(head string) bool {
+	if strings.HasPrefix(head, "ref: refs/") {
+		return true
+	}
+	if len(head) != 40 && len(head) != 64 {
+		return false
+	}
+	return strings.Trim(head, "0123456789abcdef") == ""
+}
+
+// bareWorktrees returns the linked worktrees of the bare repository bare,
+// as registered under its worktrees directory. Worktrees whose directory
+// is gone are left out, like `git worktree list` marks them prunable.
+func bareWorktrees(fs filesystem.FileSystem, bare Project) []Project {
+	adminDir := filepath.Join(bare.Path, "worktrees")
+	entries, err := fs.ReadDir(adminDir).Get()
+	if err != nil {
+		return nil
+	}
+
+	var worktrees []Project
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(filepath.Join(adminDir, entry.Name(), "gitdir")).Get()
+		if err != nil {
+			continue
+		}
+
+		gitFile := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(gitFile) {
+			gitFile = filepath.Join(adminDir, entry.Name(), gitFile)
+		}
+		dir := filepath.Dir(filepath.Clean(gitFile))
+		if info, err := fs.Stat(dir).Get(); err != nil || !info.IsDir() {
+			continue
+		}
+
+		worktrees = append(worktrees, Project{
+			Name:     filepath.Base(dir),
+			Path:     dir,
+			VCS:      VCSGit,
+			MainRepo: bare.Path,
+		})
+	}
+	return worktrees
+}
+
+// GroupWorktrees moves the worktrees of each bare repository in list to
+// right after it, keeping the order of everything else.
+func GroupWorktrees(list []Project) []Project {
+	children := make(map[string][]Project)
+	for _, p := range list {
+		if p.Bare {
+			children[p.Path] = nil
+		}
+	}
+	if len(children) == 0 {
+		return list
+	}
+
+	var rest []Project
+	for _, p := range list {
+		if _, ok := children[p.MainRepo]; ok && p.MainRepo != "" {
+			children[p.MainRepo] = append(children[p.MainRepo], p)
+			continue
+		}
+		rest = append(rest, p)
+	}
+
+	grouped := make([]Project, 0, len(list))
+	for _, p := range rest {
+		grouped = append(grouped, p)
+		if p.Bare {
+			grouped = append(grouped, children[p.Path]...)
+		}
+	}
+	return grouped
+}
+
+// gitFileProject follows the "gitdir:" pointer of a ".git" file back to the
+// repository that owns it.
+func gitFileProject(fs filesystem.FileSystem, dir string) mo.Result[Project] {
+	gitDir, err := readGitFile(fs, dir).Get()
+	if err != nil {
+		return mo.Err[Project](err)
+	}
+
+	return mo.Ok(Project{
+		Name:     filepath.Base(dir),
+		Path:     dir,
+		VCS:      VCSGit,
+		MainRepo: mainRepoFromGitDir(gitDir),
+	})
+}
+
+// mainRepoFromGitDir maps a worktree gitdir (<repo>/.git/worktrees/<name>)
+// or submodule gitdir (<repo>/.git/modules/<name>) to <repo>. Worktrees of a
+// bare repository map to the bare directory itself. A gitdir that is neither,
+// such as one created with --separate-git-dir, belongs to no other checkout.
+func mainRepoFromGitDir(gitDir string) string {
+	sep := string(filepath.Separator)
+	for _, marker := range []string{"worktrees", "modules"} {
+		if i := strings.Index(gitDir, sep+".git"+sep+marker+sep); i >= 0 {
+			return gitDir[:i]
+		}
+	}
+
+	if i := strings.LastIndex(gitDir, sep+"worktrees"+sep); i > 0 {
+		return gitDir[:i]
+	}
+
+	return ""
+}
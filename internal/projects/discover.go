@@ -1,10 +1,16 @@
 package projects
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"dev/internal/filesystem"
 
@@ -12,58 +18,195 @@ import (
 	"github.com/samber/mo"
 )
 
+// DefaultMaxDepth is how many levels below each expanded search path are
+// walked when neither a flag, DEV_MAX_DEPTH nor a per-path override is set.
+const DefaultMaxDepth = 2
+
 type Project struct {
 	Name string
 	Path string
+	VCS  VCS
+	// MainRepo is the repository a linked worktree or submodule checkout
+	// belongs to. It is empty for ordinary repositories.
+	MainRepo string
+	// Parent is the workspace root that declares this project as a member.
+	Parent string
+	// Bare is set for a git repository without a working tree.
+	Bare bool
+	// Head is what a git project has checked out, when Options.ReadGitInfo
+	// is set.
+	Head Head
+	// Remote identifies a git project by its origin as host/owner/repo,
+	// when Options.ReadGitInfo is set and the origin is hosted.
+	Remote string
+	// LastActivity is when the project was last worked on, when
+	// Options.ReadActivity is set.
+	LastActivity time.Time
+	// Pinned is set for projects pinned with `dev pin`, which are listed
+	// first.
+	Pinned bool
+	// Hidden is set for projects hidden from the list that are shown
+	// anyway.
+	Hidden bool
+}
+
+type Options struct {
+	MaxDepth mo.Option[int]
+	// Detectors defaults to DefaultDetectors when nil.
+	Detectors []Detector
+	// Markers are file name patterns that make a directory a project even
+	// without version control. Falls back to DEV_MARKERS when empty.
+	Markers []string
+	// Nested keeps walking below a detected project to find sub-projects.
+	Nested bool
+	// SkipWorkspaces stops workspace manifests such as go.work or
+	// pnpm-workspace.yaml from adding their members as projects.
+	SkipWorkspaces bool
+	// Excludes are .gitignore style patterns, relative to each search path,
+	// for directories that are never walked. Falls back to DEV_EXCLUDE.
+	Excludes []string
+	// HiddenDirs are names, or name patterns, of hidden directories that are
+	// walked like any other. Falls back to DEV_HIDDEN_DIRS.
+	HiddenDirs []string
+	// Hidden walks every hidden directory except version control metadata.
+	Hidden bool
+	// FollowSymlinks descends into symlinked directories. Projects reached
+	// through several links are listed once, and links back into their own
+	// ancestors are not followed.
+	FollowSymlinks bool
+	// RecordSkipped lists every directory left out of the walk in the scan's
+	// report, which takes memory on large trees.
+	RecordSkipped bool
+	// ReadGitInfo fills in the branch, commit and remote of git projects.
+	ReadGitInfo bool
+	// ReadActivity fills in when each project was last worked on.
+	ReadActivity bool
+	// Concurrency is how many directories are read at once. Zero picks a
+	// few per CPU.
+	Concurrency int
+}
+
+// searchPath is a directory to walk along with what applies below it.
+type searchPath struct {
+	path     string
+	maxDepth int
+	ignore   *ignorer
+	// realPath is path with symlinks resolved, tracked only when following
+	// them.
+	realPath string
+}
+
+// fileKey is the device and inode of a directory.
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// found is a project on its way to the scan, keyed by the path it is
+// deduplicated on.
+type found struct {
+	project Project
+	key     string
 }
 
-func Discover(fs filesystem.FileSystem, args []string) mo.Result[[]Project] {
-	searchPaths, err := expandPaths(fs, resolvePaths(args)).Get()
+func Discover(ctx context.Context, fs filesystem.FileSystem, args []string, opts Options) mo.Result[[]Project] {
+	scan, err := Stream(ctx, fs, args, opts).Get()
 	if err != nil {
 		return mo.Err[[]Project](err)
 	}
 
-	if len(searchPaths) == 0 {
-		return mo.Ok([]Project{})
+	result, errs := scan.Wait()
+	if len(result) == 0 && len(errs) > 0 {
+		return mo.Err[[]Project](errs[0])
 	}
 
-	var wg sync.WaitGroup
-	resultCh := make(chan Project, 64)
-	errCh := make(chan error, 64)
+	return mo.Ok(result)
+}
 
-	for _, sp := range searchPaths {
-		wg.Add(1)
-		go func(searchPath string) {
-			defer wg.Done()
-			walkRecursive(fs, searchPath, 0, resultCh, errCh)
-		}(sp)
+// Stream starts discovery in the background and returns once the options
+// are validated, so callers can show projects while the walk is running.
+// When ctx ends the scan completes with what it found so far, and the
+// search paths that had not finished are reported by Scan.TimedOut.
+func Stream(ctx context.Context, fs filesystem.FileSystem, args []string, opts Options) mo.Result[*Scan] {
+	maxDepth, err := resolveMaxDepth(opts.MaxDepth).Get()
+	if err != nil {
+		return mo.Err[*Scan](err)
 	}
 
-	go func() {
-		wg.Wait()
-		close(resultCh)
-		close(errCh)
-	}()
+	detectors, err := resolveDetectors(opts.Detectors, opts.Markers).Get()
+	if err != nil {
+		return mo.Err[*Scan](err)
+	}
 
-	seen := make(map[string]struct{})
-	result := make([]Project, 0, 64)
-	for p := range resultCh {
-		if _, exists := seen[p.Path]; !exists {
-			seen[p.Path] = struct{}{}
-			result = append(result, p)
-		}
+	excludes := resolveExcludes(opts.Excludes)
+	if _, err := newIgnorer(nil, "", excludes); err != nil {
+		return mo.Err[*Scan](err)
 	}
 
-	var errs []error
-	for err := range errCh {
-		errs = append(errs, err)
+	hiddenDirs, err := resolveHiddenDirs(opts.HiddenDirs).Get()
+	if err != nil {
+		return mo.Err[*Scan](err)
 	}
 
-	if len(result) == 0 && len(errs) > 0 {
-		return mo.Err[[]Project](errs[0])
+	scan := newScan(ctx)
+	resultCh := make(chan found, 64)
+	errCh := make(chan PathError, 64)
+	w := &walker{
+		ctx:        ctx,
+		fs:         fs,
+		detectors:  detectors,
+		nested:     opts.Nested,
+		workspaces: !opts.SkipWorkspaces,
+		hidden:     opts.Hidden,
+		hiddenDirs: hiddenDirs,
+		follow:     opts.FollowSymlinks,
+		record:     opts.RecordSkipped,
+		gitInfo:    opts.ReadGitInfo,
+		activity:   opts.ReadActivity,
+		scan:       scan,
+		out:        resultCh,
+		errCh:      errCh,
 	}
 
-	return mo.Ok(result)
+	// Search paths are expanded on the pool too, since reading one can hang
+	// just like any directory below it.
+	pool := newPool(lo.Ternary(opts.Concurrency > 0, opts.Concurrency, defaultConcurrency()))
+	for _, root := range parseSearchPaths(fs, resolvePaths(args), maxDepth) {
+		scan.track(walkRoot{search: root.path, dir: root.path})
+		pool.submit(func(spawn func(task)) {
+			defer scan.untrack(walkRoot{search: root.path, dir: root.path})
+
+			root.ignore, _ = newIgnorer(nil, root.path, excludes)
+			if w.follow {
+				realPath, err := fs.EvalSymlinks(root.path).Get()
+				if err != nil {
+					w.fail(root.path, err)
+					return
+				}
+				root.realPath = realPath
+			}
+
+			children, err := w.expandPath(root).Get()
+			if err != nil {
+				w.fail(root.path, err)
+				return
+			}
+
+			for _, sp := range children {
+				spawn(w.walkTask(sp, 0, walkRoot{search: root.path, dir: sp.path}))
+			}
+		})
+	}
+
+	go func() {
+		pool.run(ctx)
+		close(resultCh)
+		close(errCh)
+	}()
+
+	go scan.collect(ctx, resultCh, errCh)
+
+	return mo.Ok(scan)
 }
 
 func resolvePaths(args []string) []string {
@@ -83,79 +226,347 @@ func resolvePaths(args []string) []string {
 	return []string{}
 }
 
-func expandPaths(fs filesystem.FileSystem, searchPaths []string) mo.Result[[]string] {
-	if len(searchPaths) == 0 {
-		return mo.Ok([]string{})
+func resolveExcludes(excludes []string) []string {
+	if len(excludes) > 0 {
+		return excludes
 	}
+	return strings.Fields(os.Getenv("DEV_EXCLUDE"))
+}
 
-	results := lo.Map(searchPaths, func(p string, _ int) mo.Result[[]string] {
-		return expandPath(fs, p)
-	})
-
-	paths := lo.FlatMap(results, func(r mo.Result[[]string], _ int) []string {
-		return r.OrElse([]string{})
-	})
+func resolveHiddenDirs(hiddenDirs []string) mo.Result[[]string] {
+	if len(hiddenDirs) == 0 {
+		hiddenDirs = strings.Fields(os.Getenv("DEV_HIDDEN_DIRS"))
+	}
 
-	errors := lo.FilterMap(results, func(r mo.Result[[]string], _ int) (error, bool) {
-		if r.IsError() {
-			return r.Error(), true
+	for _, pattern := range hiddenDirs {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return mo.Err[[]string](fmt.Errorf("invalid hidden directory %q: %w", pattern, err))
 		}
-		return nil, false
-	})
+	}
 
-	if len(paths) == 0 && len(errors) > 0 {
-		return mo.Err[[]string](errors[0])
+	return mo.Ok(hiddenDirs)
+}
+
+func resolveDetectors(detectors []Detector, markers []string) mo.Result[[]Detector] {
+	if detectors == nil {
+		detectors = DefaultDetectors()
+	}
+
+	if len(markers) == 0 {
+		markers = strings.Fields(os.Getenv("DEV_MARKERS"))
+	}
+	if len(markers) == 0 {
+		return mo.Ok(detectors)
 	}
 
-	return mo.Ok(paths)
+	for _, pattern := range markers {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return mo.Err[[]Detector](fmt.Errorf("invalid marker %q: %w", pattern, err))
+		}
+	}
+
+	return mo.Ok(append(detectors[:len(detectors):len(detectors)], GlobDetector{Patterns: markers}))
 }
 
-func expandPath(fs filesystem.FileSystem, searchPath string) mo.Result[[]string] {
-	entries, err := fs.ReadDir(searchPath).Get()
-	if err != nil {
-		return mo.Err[[]string](err)
+func resolveMaxDepth(maxDepth mo.Option[int]) mo.Result[int] {
+	if depth, ok := maxDepth.Get(); ok {
+		if depth < 0 {
+			return mo.Err[int](fmt.Errorf("invalid max depth %d", depth))
+		}
+		return mo.Ok(depth)
 	}
 
-	paths := lo.FilterMap(entries, func(entry os.DirEntry, _ int) (string, bool) {
-		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-			return filepath.Join(searchPath, entry.Name()), true
+	if env := os.Getenv("DEV_MAX_DEPTH"); env != "" {
+		depth, err := strconv.Atoi(env)
+		if err != nil || depth < 0 {
+			return mo.Err[int](fmt.Errorf("invalid DEV_MAX_DEPTH %q", env))
 		}
-		return "", false
+		return mo.Ok(depth)
+	}
+
+	return mo.Ok(DefaultMaxDepth)
+}
+
+// parseSearchPaths splits an optional ":depth" suffix off each path, so
+// "~/src:5" walks five levels below ~/src regardless of the global depth.
+// A path that exists as written, such as /mnt/data:2024, keeps its suffix.
+func parseSearchPaths(fs filesystem.FileSystem, paths []string, maxDepth int) []searchPath {
+	return lo.Map(paths, func(p string, _ int) searchPath {
+		if i := strings.LastIndex(p, ":"); i > 0 && fs.Stat(p).IsError() {
+			if depth, err := strconv.Atoi(p[i+1:]); err == nil && depth >= 0 {
+				return searchPath{path: p[:i], maxDepth: depth}
+			}
+		}
+		return searchPath{path: p, maxDepth: maxDepth}
 	})
+}
 
-	return mo.Ok(paths)
+func (w *walker) expandPath(sp searchPath) mo.Result[[]searchPath] {
+	entries, err := w.fs.ReadDir(sp.path).Get()
+	if err != nil {
+		return mo.Err[[]searchPath](err)
+	}
+
+	return mo.Ok(w.children(sp, entries))
+}
+
+type walker struct {
+	ctx        context.Context
+	fs         filesystem.FileSystem
+	detectors  []Detector
+	nested     bool
+	workspaces bool
+	hidden     bool
+	hiddenDirs []string
+	follow     bool
+	visited    sync.Map
+	record     bool
+	gitInfo    bool
+	activity   bool
+	scan       *Scan
+	out        chan<- found
+	errCh      chan<- PathError
 }
 
-func walkRecursive(fs filesystem.FileSystem, dir string, depth int, out chan<- Project, errCh chan<- error) {
-	if depth > 2 {
+// walkTask walks sp on the pool. The walk is counted towards root until it
+// and everything it spawns has finished.
+func (w *walker) walkTask(sp searchPath, depth int, root walkRoot) task {
+	w.scan.track(root)
+	return func(spawn func(task)) {
+		defer w.scan.untrack(root)
+		w.walk(sp, depth, root, spawn)
+	}
+}
+
+func (w *walker) walk(sp searchPath, depth int, root walkRoot, spawn func(task)) {
+	if w.ctx.Err() != nil {
+		return
+	}
+	if depth > sp.maxDepth {
+		w.skip(sp.path, SkipDepth)
 		return
 	}
 
-	entries, err := fs.ReadDir(dir).Get()
+	entries, err := w.fs.ReadDir(sp.path).Get()
 	if err != nil {
-		errCh <- err
+		w.fail(sp.path, err)
 		return
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	if p, ok := w.detect(sp.path, entries); ok {
+		w.emit(sp, p)
+		if p.Bare {
+			// A bare repository holds only git's own data, so there is
+			// nothing to walk, but its worktrees may live anywhere.
+			w.emitWorktrees(sp, p)
+			return
+		}
+		if w.workspaces {
+			w.emitWorkspaceMembers(sp, p, entries)
+		}
+		if !w.nested {
+			return
+		}
+	}
+
+	for _, child := range w.children(sp, entries) {
+		spawn(w.walkTask(child, depth+1, root))
+	}
+}
+
+// children returns the directories among entries of sp that the walk
+// descends into, picking up sp's .devignore on the way.
+func (w *walker) children(sp searchPath, entries []os.DirEntry) []searchPath {
+	ignore := w.loadIgnoreFile(sp.path, entries, sp.ignore)
+
+	return lo.FilterMap(entries, func(entry os.DirEntry, _ int) (searchPath, bool) {
+		isLink := entry.Type()&os.ModeSymlink != 0
+		if !entry.IsDir() && !(isLink && w.follow) {
+			if isLink && w.record {
+				path := filepath.Join(sp.path, entry.Name())
+				if info, err := w.fs.Stat(path).Get(); err == nil && info.IsDir() {
+					w.skip(path, SkipSymlink)
+				}
+			}
+			return searchPath{}, false
 		}
 
 		name := entry.Name()
+		path := filepath.Join(sp.path, name)
+		if w.skipDir(name) {
+			w.skip(path, SkipHidden)
+			return searchPath{}, false
+		}
+
+		if ignore.ignored(path) {
+			w.skip(path, SkipExcluded)
+			return searchPath{}, false
+		}
+
+		child := searchPath{path: path, maxDepth: sp.maxDepth, ignore: ignore}
+		switch {
+		case isLink:
+			realPath, ok := w.followLink(sp, path)
+			child.realPath = realPath
+			return child, ok
+		case w.follow:
+			child.realPath = filepath.Join(sp.realPath, name)
+		}
+		return child, true
+	})
+}
+
+// followLink resolves a symlink below sp and reports whether the directory
+// it points to should be walked: not when it is sp or one of sp's ancestors,
+// which would loop, and not when another link already led there.
+func (w *walker) followLink(sp searchPath, path string) (string, bool) {
+	realPath, err := w.fs.EvalSymlinks(path).Get()
+	if err != nil {
+		w.fail(path, err)
+		return "", false
+	}
+
+	info, err := w.fs.Stat(realPath).Get()
+	if err != nil {
+		w.fail(path, err)
+		return "", false
+	}
+	if !info.IsDir() {
+		return "", false
+	}
+	if isWithin(sp.realPath, realPath) {
+		w.skip(path, SkipLinkLoop)
+		return "", false
+	}
+
+	if id, ok := fileID(info); ok {
+		if _, seen := w.visited.LoadOrStore(id, struct{}{}); seen {
+			w.skip(path, SkipLinkRepeat)
+			return "", false
+		}
+	}
+	return realPath, true
+}
+
+// skipDir reports whether a directory is left out of the walk by its name
+// alone, which is the case for hidden directories unless they are allowed.
+func (w *walker) skipDir(name string) bool {
+	if len(name) == 0 || name[0] != '.' {
+		return false
+	}
+	if w.hidden {
+		return metadataDirs[name]
+	}
+	return !slices.ContainsFunc(w.hiddenDirs, func(pattern string) bool {
+		ok, _ := filepath.Match(pattern, name)
+		return ok && !metadataDirs[name]
+	})
+}
+
+// loadIgnoreFile adds the rules of dir's .devignore, if entries has one, to
+// those inherited from above. A file that cannot be used is reported and the
+// inherited rules apply unchanged.
+func (w *walker) loadIgnoreFile(dir string, entries []os.DirEntry, parent *ignorer) *ignorer {
+	if !slices.ContainsFunc(entries, func(e os.DirEntry) bool { return e.Name() == ignoreFile && !e.IsDir() }) {
+		return parent
+	}
+
+	path := filepath.Join(dir, ignoreFile)
+	data, err := w.fs.ReadFile(path).Get()
+	if err != nil {
+		w.fail(path, err)
+		return parent
+	}
 
-		if name == ".git" {
-			out <- Project{
-				Name: filepath.Base(dir),
-				Path: dir,
+	ignore, err := newIgnorer(parent, dir, strings.Split(string(data), "\n"))
+	if err != nil {
+		w.fail(path, err)
+		return parent
+	}
+	return ignore
+}
+
+// detect asks each detector in priority order whether one of entries marks
+// dir as a project. A marker that cannot be read is reported and skipped.
+func (w *walker) detect(dir string, entries []os.DirEntry) (Project, bool) {
+	for _, d := range w.detectors {
+		for _, entry := range entries {
+			if !d.Match(entry) {
+				continue
+			}
+			p, err := d.Project(w.fs, dir, entry).Get()
+			if errors.Is(err, ErrNotProject) {
+				continue
+			}
+			if err != nil {
+				w.fail(filepath.Join(dir, entry.Name()), err)
+				continue
+			}
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+func (w *walker) emitWorkspaceMembers(sp searchPath, parent Project, entries []os.DirEntry) {
+	members, errs := workspaceMembers(w.fs, parent, entries)
+	for _, e := range errs {
+		w.fail(e.Path, e.Err)
+	}
+	for _, m := range members {
+		member := sp
+		member.path = m.Path
+		if sp.realPath != "" {
+			if rel, err := filepath.Rel(sp.path, m.Path); err == nil {
+				member.realPath = filepath.Join(sp.realPath, rel)
 			}
-			return
 		}
+		w.emit(member, m)
+	}
+}
 
-		if len(name) > 0 && name[0] == '.' {
-			continue
+func (w *walker) emitWorktrees(sp searchPath, bare Project) {
+	for _, wt := range bareWorktrees(w.fs, bare) {
+		worktree := sp
+		worktree.path = wt.Path
+		worktree.realPath = ""
+		if w.follow {
+			worktree.realPath = w.fs.EvalSymlinks(wt.Path).OrElse(wt.Path)
 		}
+		w.emit(worktree, wt)
+	}
+}
+
+// emit and fail give up once the scan has ended, since nothing reads the
+// channels after that. Projects are keyed by their real path when symlinks
+// are followed, so one reached through several links is listed once.
+func (w *walker) emit(sp searchPath, p Project) {
+	key := sp.path
+	if sp.realPath != "" {
+		key = sp.realPath
+	}
+	if w.gitInfo {
+		p = withGitInfo(w.fs, p)
+	}
+	if w.activity {
+		p.LastActivity = LastActivity(w.fs, p)
+	}
+
+	select {
+	case w.out <- found{project: p, key: key}:
+	case <-w.ctx.Done():
+	}
+}
+
+func (w *walker) fail(path string, err error) {
+	select {
+	case w.errCh <- PathError{Path: path, Err: err}:
+	case <-w.ctx.Done():
+	}
+}
 
-		walkRecursive(fs, filepath.Join(dir, name), depth+1, out, errCh)
+func (w *walker) skip(path string, reason SkipReason) {
+	if w.record {
+		w.scan.skip(path, reason)
 	}
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package projects
+
+import "os"
+
+// fileID is unavailable here, so symlinked directories are told apart by
+// their resolved paths alone.
+func fileID(os.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package projects
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a directory by device and inode, so the same directory
+// is recognized whichever symlink leads to it.
+func fileID(info os.FileInfo) (fileKey, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
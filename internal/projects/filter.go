@@ -1,14 +1,32 @@
 package projects
 
 import (
+	"cmp"
+	"slices"
 	"sort"
 	"strings"
 	"unicode"
+
+	"github.com/samber/lo"
 )
 
-func Filter(projects []Project, query string) []Project {
+// Filter returns the projects matching query, best matches first, with
+// ties broken by rank, keyed by path, such as frecency. Without a query it
+// orders projects by rank alone, with pinned projects first. Projects
+// without a rank keep their order, after those with one.
+func Filter(projects []Project, query string, rank map[string]float64) []Project {
 	if query == "" {
-		return projects
+		if len(rank) == 0 && !slices.ContainsFunc(projects, func(p Project) bool { return p.Pinned }) {
+			return projects
+		}
+		ranked := slices.Clone(projects)
+		slices.SortStableFunc(ranked, func(a, b Project) int {
+			if a.Pinned != b.Pinned {
+				return lo.Ternary(a.Pinned, -1, 1)
+			}
+			return cmp.Compare(rank[b.Path], rank[a.Path])
+		})
+		return ranked
 	}
 
 	query = strings.ToLower(query)
@@ -22,13 +40,18 @@ func Filter(projects []Project, query string) []Project {
 	for i, p := range projects {
 		nameScore := FuzzyScore(query, strings.ToLower(p.Name))
 		pathScore := FuzzyScore(query, strings.ToLower(p.Path))
-		if bestScore := max(pathScore, nameScore); bestScore > 0 {
+		gitScore := max(FuzzyScore(query, strings.ToLower(p.Remote)), FuzzyScore(query, strings.ToLower(p.Head.Branch)))
+		gitScore = max(gitScore, commitScore(query, p.Head.Commit))
+		if bestScore := max(max(pathScore, nameScore), gitScore); bestScore > 0 {
 			matches = append(matches, scored{idx: i, score: bestScore})
 		}
 	}
 
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].score > matches[j].score
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return rank[projects[matches[i].idx].Path] > rank[projects[matches[j].idx].Path]
 	})
 
 	result := make([]Project, len(matches))
@@ -39,6 +62,15 @@ func Filter(projects []Project, query string) []Project {
 	return result
 }
 
+// commitScore matches a commit only by prefix, since a fuzzy match on a
+// hash matches almost anything.
+func commitScore(query, commit string) int {
+	if commit == "" || !strings.HasPrefix(commit, query) {
+		return 0
+	}
+	return FuzzyScore(query, commit)
+}
+
 const (
 	scoreMatch        = 1
 	scoreConsecutive  = 2
@@ -0,0 +1,45 @@
+package projects
+
+import (
+	"math"
+	"time"
+)
+
+// frecencyHalfLife is how long it takes a visit to count half as much.
+const frecencyHalfLife = 7 * 24 * time.Hour
+
+// Visit is how often a project was opened and when it last was.
+type Visit struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// Visits are the visits of projects, keyed by path.
+type Visits map[string]Visit
+
+// Record counts a visit to path at now.
+func (v Visits) Record(path string, now time.Time) {
+	visit := v[path]
+	visit.Count++
+	visit.Last = now
+	v[path] = visit
+}
+
+// Frecency scores a visit by how often and how recently it happened: the
+// visit count, halved for every frecencyHalfLife since the last one.
+func (v Visit) Frecency(now time.Time) float64 {
+	age := now.Sub(v.Last)
+	if age < 0 {
+		age = 0
+	}
+	return float64(v.Count) * math.Pow(0.5, float64(age)/float64(frecencyHalfLife))
+}
+
+// Scores returns the frecency of every visited path.
+func (v Visits) Scores(now time.Time) map[string]float64 {
+	scores := make(map[string]float64, len(v))
+	for path, visit := range v {
+		scores[path] = visit.Frecency(now)
+	}
+	return scores
+}
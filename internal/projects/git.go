@@ -0,0 +1,186 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/mo"
+)
+
+// shortCommitLen is how many characters of a commit hash are shown, as
+// git does by default for small repositories.
+const shortCommitLen = 7
+
+// maxSymrefDepth bounds how many symbolic refs are followed, as a guard
+// against refs pointing at each other.
+const maxSymrefDepth = 5
+
+// Head is what a git checkout has checked out.
+type Head struct {
+	// Branch is empty when the head is detached.
+	Branch string
+	// Commit is the short hash of the checked out commit. It is empty on a
+	// branch without commits.
+	Commit   string
+	Detached bool
+}
+
+// ReadHead reads the head of the git project p straight from its HEAD,
+// refs and packed-refs files.
+func ReadHead(fs filesystem.FileSystem, p Project) mo.Result[Head] {
+	gitDir, err := gitDirOf(fs, p).Get()
+	if err != nil {
+		return mo.Err[Head](err)
+	}
+	commonDir := commonDirOf(fs, gitDir)
+
+	data, err := fs.ReadFile(filepath.Join(gitDir, "HEAD")).Get()
+	if err != nil {
+		return mo.Err[Head](err)
+	}
+	head := strings.TrimSpace(string(data))
+
+	ref, symbolic := strings.CutPrefix(head, "ref: ")
+	if !symbolic {
+		if !isGitHead(head) {
+			return mo.Err[Head](fmt.Errorf("%s: invalid HEAD", gitDir))
+		}
+		return mo.Ok(Head{Commit: head[:shortCommitLen], Detached: true})
+	}
+
+	ref = strings.TrimSpace(ref)
+	commit, err := resolveRef(fs, gitDir, commonDir, ref).Get()
+	if err != nil {
+		return mo.Err[Head](err)
+	}
+	return mo.Ok(Head{
+		Branch: strings.TrimPrefix(ref, "refs/heads/"),
+		Commit: shortCommit(commit),
+	})
+}
+
+// withGitInfo returns p with the head and remote of a git project read in.
+// What cannot be read is left empty.
+func withGitInfo(fs filesystem.FileSystem, p Project) Project {
+	if p.VCS != VCSGit {
+		return p
+	}
+	p.Head = ReadHead(fs, p).OrEmpty()
+	p.Remote = ReadRemote(fs, p).OrEmpty()
+	return p
+}
+
+// gitDirOf returns the directory git keeps p's metadata in: p itself when
+// bare, its ".git" directory, or wherever its ".git" file points.
+func gitDirOf(fs filesystem.FileSystem, p Project) mo.Result[string] {
+	if p.Bare {
+		return mo.Ok(p.Path)
+	}
+
+	dotGit := filepath.Join(p.Path, ".git")
+	info, err := fs.Stat(dotGit).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	if info.IsDir() {
+		return mo.Ok(dotGit)
+	}
+	return readGitFile(fs, p.Path)
+}
+
+// readGitFile returns the directory the "gitdir:" line of dir's ".git" file
+// points to.
+func readGitFile(fs filesystem.FileSystem, dir string) mo.Result[string] {
+	gitFile := filepath.Join(dir, ".git")
+	data, err := fs.ReadFile(gitFile).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return mo.Err[string](fmt.Errorf("%s: missing gitdir pointer", gitFile))
+	}
+
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return mo.Ok(filepath.Clean(gitDir))
+}
+
+// commonDirOf returns where the refs shared by all worktrees of gitDir
+// live. Linked worktrees name it in their "commondir" file.
+func commonDirOf(fs filesystem.FileSystem, gitDir string) string {
+	data, err := fs.ReadFile(filepath.Join(gitDir, "commondir")).Get()
+	if err != nil {
+		return gitDir
+	}
+
+	commonDir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// resolveRef returns the commit ref points at, or "" for a branch that has
+// no commits yet. Per-worktree refs are looked up in gitDir first, then
+// loose and packed refs in commonDir.
+func resolveRef(fs filesystem.FileSystem, gitDir, commonDir, ref string) mo.Result[string] {
+	for range maxSymrefDepth {
+		value, ok := readLooseRef(fs, gitDir, ref)
+		if !ok {
+			value, ok = readLooseRef(fs, commonDir, ref)
+		}
+		if !ok {
+			value, ok = readPackedRef(fs, commonDir, ref)
+		}
+		if !ok {
+			return mo.Ok("")
+		}
+
+		target, symbolic := strings.CutPrefix(value, "ref: ")
+		if !symbolic {
+			if !isGitHead(value) {
+				return mo.Err[string](fmt.Errorf("%s: invalid ref %s", commonDir, ref))
+			}
+			return mo.Ok(value)
+		}
+		ref = strings.TrimSpace(target)
+	}
+	return mo.Err[string](errors.New("too many levels of symbolic refs"))
+}
+
+func readLooseRef(fs filesystem.FileSystem, dir, ref string) (string, bool) {
+	data, err := fs.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))).Get()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// readPackedRef looks ref up in packed-refs, whose lines are "<hash> <ref>"
+// with comments and peeled tags ("^<hash>") in between.
+func readPackedRef(fs filesystem.FileSystem, dir, ref string) (string, bool) {
+	data, err := fs.ReadFile(filepath.Join(dir, "packed-refs")).Get()
+	if err != nil {
+		return "", false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		hash, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && name == ref && !strings.HasPrefix(hash, "#") && !strings.HasPrefix(hash, "^") {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+func shortCommit(commit string) string {
+	return commit[:min(len(commit), shortCommitLen)]
+}
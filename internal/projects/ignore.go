@@ -0,0 +1,133 @@
+package projects
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFile is read from any walked directory and excludes paths below it
+// with the same pattern syntax as .gitignore.
+const ignoreFile = ".devignore"
+
+type ignoreRule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// ignorer holds the rules of one ignore file, or of the global excludes,
+// chained to the rules that apply to the directories above it.
+type ignorer struct {
+	parent *ignorer
+	base   string
+	rules  []ignoreRule
+}
+
+// newIgnorer parses patterns relative to base and places them below parent.
+// A nil parent starts a new chain.
+func newIgnorer(parent *ignorer, base string, patterns []string) (*ignorer, error) {
+	rules := make([]ignoreRule, 0, len(patterns))
+	for _, line := range patterns {
+		rule, ok, err := parseIgnoreRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", line, err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == 0 {
+		return parent, nil
+	}
+	return &ignorer{parent: parent, base: base, rules: rules}, nil
+}
+
+// ignored reports whether path is excluded. As with .gitignore, rules from
+// deeper files win over shallower ones and later rules over earlier ones.
+func (ig *ignorer) ignored(path string) bool {
+	for cur := ig; cur != nil; cur = cur.parent {
+		rel, err := filepath.Rel(cur.base, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for i := len(cur.rules) - 1; i >= 0; i-- {
+			if cur.rules[i].re.MatchString(rel) {
+				return !cur.rules[i].negate
+			}
+		}
+	}
+	return false
+}
+
+// parseIgnoreRule compiles one .gitignore style line. Blank lines and
+// comments yield no rule.
+func parseIgnoreRule(line string) (ignoreRule, bool, error) {
+	pattern := strings.TrimRight(line, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return ignoreRule{}, false, nil
+	}
+
+	negate := false
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		negate = true
+		pattern = rest
+	}
+	pattern = strings.TrimPrefix(pattern, `\`)
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return ignoreRule{}, false, nil
+	}
+
+	// A slash anywhere but at the end anchors the pattern to the directory
+	// that declares it, otherwise it matches a name at any depth.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	prefix := "^(?:.*/)?"
+	if anchored {
+		prefix = "^"
+	}
+
+	re, err := regexp.Compile(prefix + globToRegexp(pattern) + "$")
+	if err != nil {
+		return ignoreRule{}, false, err
+	}
+	return ignoreRule{re: re, negate: negate}, true, nil
+}
+
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package projects
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// DisplayNames returns the name to show for each project of list, keyed by
+// path. Projects whose name is shared with others get the fewest parent
+// directories in front of it that tell them apart, like "work/backend" and
+// "personal/backend".
+func DisplayNames(list []Project) map[string]string {
+	names := make(map[string]string, len(list))
+
+	for name, group := range lo.GroupBy(list, func(p Project) string { return p.Name }) {
+		if len(group) == 1 {
+			names[group[0].Path] = name
+			continue
+		}
+
+		parents := lo.Map(group, func(p Project, _ int) []string {
+			return parentDirs(p.Path)
+		})
+		for i, p := range group {
+			names[p.Path] = uniqueSuffix(name, parents, i)
+		}
+	}
+
+	return names
+}
+
+// uniqueSuffix prefixes name with as many of parents[i], innermost first,
+// as it takes for no other entry of parents to end the same way.
+func uniqueSuffix(name string, parents [][]string, i int) string {
+	own := parents[i]
+	for depth := 1; depth <= len(own); depth++ {
+		suffix := own[len(own)-depth:]
+		clash := false
+		for j, other := range parents {
+			if j != i && len(other) >= depth && slices.Equal(other[len(other)-depth:], suffix) {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			return filepath.Join(append(slices.Clone(suffix), name)...)
+		}
+	}
+	return filepath.Join(append(slices.Clone(own), name)...)
+}
+
+// parentDirs splits the directory containing path into its components.
+func parentDirs(path string) []string {
+	dir := filepath.Dir(filepath.Clean(path))
+	return lo.Filter(strings.Split(filepath.ToSlash(dir), "/"), func(s string, _ int) bool {
+		return s != ""
+	})
+}
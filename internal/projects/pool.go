@@ -0,0 +1,125 @@
+package projects
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// task is one unit of walk work. It hands the work it discovers, such as
+// the subdirectories of the directory it read, to spawn.
+type task func(spawn func(task))
+
+// pool runs tasks on a fixed number of workers. Each worker keeps its own
+// deque and takes from its back, so it walks depth first, while idle workers
+// steal from the front of the others. One large subtree is thereby spread
+// across every worker instead of serializing on the one that found it.
+type pool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  [][]task
+	next    int
+	pending int
+}
+
+// defaultConcurrency is the number of workers when Options.Concurrency is
+// not set. Reading directories mostly waits on the disk, so it is a few
+// times the number of CPUs.
+func defaultConcurrency() int {
+	return max(4*runtime.GOMAXPROCS(0), 8)
+}
+
+func newPool(workers int) *pool {
+	p := &pool{queues: make([][]task, max(workers, 1))}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// submit queues a task from outside the workers. Submitted tasks are spread
+// round robin so the first ones start right away.
+func (p *pool) submit(t task) {
+	p.mu.Lock()
+	p.push(p.next, t)
+	p.next = (p.next + 1) % len(p.queues)
+	p.mu.Unlock()
+}
+
+// run blocks until every task, including those spawned by other tasks, has
+// run or ctx has ended. Queued tasks are dropped when ctx ends, but running
+// ones are waited for.
+func (p *pool) run(ctx context.Context) {
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for id := range p.queues {
+		wg.Go(func() { p.work(ctx, id) })
+	}
+	wg.Wait()
+}
+
+func (p *pool) work(ctx context.Context, id int) {
+	spawn := func(t task) {
+		p.mu.Lock()
+		p.push(id, t)
+		p.mu.Unlock()
+	}
+
+	for {
+		t, ok := p.take(ctx, id)
+		if !ok {
+			return
+		}
+		t(spawn)
+
+		p.mu.Lock()
+		if p.pending--; p.pending == 0 {
+			p.cond.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// push must be called with mu held.
+func (p *pool) push(id int, t task) {
+	p.queues[id] = append(p.queues[id], t)
+	p.pending++
+	p.cond.Signal()
+}
+
+// take returns the next task for worker id, waiting while other workers
+// are still running tasks that may spawn more. It reports false once there
+// is nothing left to do.
+func (p *pool) take(ctx context.Context, id int) (task, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if ctx.Err() != nil || p.pending == 0 {
+			return nil, false
+		}
+
+		if q := p.queues[id]; len(q) > 0 {
+			t := q[len(q)-1]
+			q[len(q)-1] = nil
+			p.queues[id] = q[:len(q)-1]
+			return t, true
+		}
+
+		for i := 1; i < len(p.queues); i++ {
+			victim := (id + i) % len(p.queues)
+			if q := p.queues[victim]; len(q) > 0 {
+				t := q[0]
+				q[0] = nil
+				p.queues[victim] = q[1:]
+				return t, true
+			}
+		}
+
+		p.cond.Wait()
+	}
+}
@@ -1,8 +1,10 @@
 package projects
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 )
 
 // generateProjects creates a slice of 'count' mock projects for benchmarking.
@@ -21,7 +23,7 @@ func BenchmarkFilter1000Projects(b *testing.B) {
 	query := "proj-9" // A query that will match some projects, testing the fuzzy logic
 
 	for b.Loop() {
-		Filter(allProjects, query)
+		Filter(allProjects, query, nil)
 	}
 }
 
@@ -30,7 +32,7 @@ func BenchmarkFilter1000Projects_NoMatch(b *testing.B) {
 	query := "nonexistentquery" // A query that will not match any projects
 
 	for b.Loop() {
-		Filter(allProjects, query)
+		Filter(allProjects, query, nil)
 	}
 }
 
@@ -39,7 +41,7 @@ func BenchmarkFilter1000Projects_ExactMatch(b *testing.B) {
 	query := "project-0500" // A query that will exactly match one project
 
 	for b.Loop() {
-		Filter(allProjects, query)
+		Filter(allProjects, query, nil)
 	}
 }
 
@@ -48,6 +50,35 @@ func BenchmarkFilter1000Projects_EmptyQuery(b *testing.B) {
 	query := "" // An empty query should return all projects without fuzzy matching
 
 	for b.Loop() {
-		Filter(allProjects, query)
+		Filter(allProjects, query, nil)
+	}
+}
+
+func benchmarkDiscover(b *testing.B, fs *mockFileSystem, concurrency int) {
+	for b.Loop() {
+		Discover(context.Background(), fs, []string{"/src:4"}, Options{Concurrency: concurrency})
+	}
+}
+
+// A tree of 8^4 = 4096 repositories below /src.
+func BenchmarkDiscoverLargeTree(b *testing.B) {
+	fs := syntheticTree(8, 4)
+	for _, n := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			benchmarkDiscover(b, fs, n)
+		})
+	}
+}
+
+// The same tree with a sleep in every directory read, so the walk waits on
+// the disk like it does on a real one. Sleeps take a timer tick or so, which
+// makes a single worker too slow to be worth measuring.
+func BenchmarkDiscoverLargeTreeSlowDisk(b *testing.B) {
+	fs := syntheticTree(8, 4)
+	fs.delay = 20 * time.Microsecond
+	for _, n := range []int{8, 64} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			benchmarkDiscover(b, fs, n)
+		})
 	}
 }
@@ -1,20 +1,34 @@
 package projects
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"math"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"dev/internal/filesystem"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
 type mockDirEntry struct {
-	name  string
-	isDir bool
+	name   string
+	isDir  bool
+	isLink bool
 }
 
 func (m *mockDirEntry) Name() string {
@@ -26,6 +40,9 @@ func (m *mockDirEntry) IsDir() bool {
 }
 
 func (m *mockDirEntry) Type() os.FileMode {
+	if m.isLink {
+		return os.ModeSymlink
+	}
 	if m.isDir {
 		return os.ModeDir
 	}
@@ -36,16 +53,94 @@ func (m *mockDirEntry) Info() (os.FileInfo, error) {
 	return nil, nil
 }
 
+type mockFileInfo struct {
+	name    string
+	isDir   bool
+	modTime time.Time
+}
+
+func (m *mockFileInfo) Name() string       { return m.name }
+func (m *mockFileInfo) Size() int64        { return 0 }
+func (m *mockFileInfo) Mode() os.FileMode  { return (&mockDirEntry{isDir: m.isDir}).Type() }
+func (m *mockFileInfo) ModTime() time.Time { return m.modTime }
+func (m *mockFileInfo) IsDir() bool        { return m.isDir }
+func (m *mockFileInfo) Sys() any           { return nil }
+
 type mockFileSystem struct {
 	dirs    map[string][]os.DirEntry
+	files   map[string]string
+	links   map[string]string
 	readErr error
+	// denied fails ReadDir on these paths only.
+	denied map[string]bool
+	// hang makes ReadDir block on these paths until release is closed,
+	// like a stuck network mount.
+	hang    map[string]bool
+	release chan struct{}
+	// delay slows every ReadDir down like a real disk would.
+	delay time.Duration
+	// modTimes are the modification times Stat reports.
+	modTimes map[string]time.Time
 }
 
 func (m *mockFileSystem) ReadDir(path string) mo.Result[[]os.DirEntry] {
+	if m.hang[path] {
+		<-m.release
+	}
+	if m.delay > 0 {
+		time.Sleep(m.delay)
+	}
 	if m.readErr != nil {
 		return mo.Err[[]os.DirEntry](m.readErr)
 	}
-	return mo.Ok(m.dirs[path])
+	if m.denied[path] {
+		return mo.Err[[]os.DirEntry](&fs.PathError{Op: "open", Path: path, Err: fs.ErrPermission})
+	}
+	return mo.Ok(m.dirs[m.EvalSymlinks(path).OrElse(path)])
+}
+
+func (m *mockFileSystem) ReadFile(path string) mo.Result[[]byte] {
+	if m.readErr != nil {
+		return mo.Err[[]byte](m.readErr)
+	}
+	content, ok := m.files[path]
+	if !ok {
+		return mo.Err[[]byte](os.ErrNotExist)
+	}
+	return mo.Ok([]byte(content))
+}
+
+func (m *mockFileSystem) Stat(path string) mo.Result[os.FileInfo] {
+	path, err := m.EvalSymlinks(path).Get()
+	if err != nil {
+		return mo.Err[os.FileInfo](err)
+	}
+	if _, ok := m.dirs[path]; ok {
+		return mo.Ok[os.FileInfo](&mockFileInfo{name: filepath.Base(path), isDir: true, modTime: m.modTimes[path]})
+	}
+	if _, ok := m.files[path]; ok {
+		return mo.Ok[os.FileInfo](&mockFileInfo{name: filepath.Base(path), modTime: m.modTimes[path]})
+	}
+	return mo.Err[os.FileInfo](os.ErrNotExist)
+}
+
+// EvalSymlinks replaces the longest linked prefix of path with its target
+// until no link is left.
+func (m *mockFileSystem) EvalSymlinks(path string) mo.Result[string] {
+	for range 32 {
+		resolved := path
+		for link, target := range m.links {
+			if rest, ok := strings.CutPrefix(path, link); ok && (rest == "" || rest[0] == '/') {
+				resolved = target + rest
+				break
+			}
+		}
+		if resolved == path {
+			return mo.Ok(path)
+		}
+		path = resolved
+	}
+	return mo.Err[string](errors.New("too many links"))
 }
 
 func (m *mockFileSystem) Chdir(path string) mo.Result[string] {
@@ -59,231 +154,1922 @@ func TestDiscover_DiscoversGitRepos(t *testing.T) {
 				&mockDirEntry{name: "project-a", isDir: true},
 				&mockDirEntry{name: "project-b", isDir: true},
 			},
-			"/home/user/project-a": {&mockDirEntry{name: ".git", isDir: true}},
-			"/home/user/project-b": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/project-a": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/project-b": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 2 {
+		t.Errorf("expected 2 projects, got %d", len(projects))
+	}
+}
+
+func TestDiscover_IgnoresNonGitDirs(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "not-a-project", isDir: true},
+				&mockDirEntry{name: "real-project", isDir: true},
+			},
+			"/home/user/not-a-project": {},
+			"/home/user/real-project":  {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Errorf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Name != "real-project" {
+		t.Errorf("expected 'real-project', got %q", projects[0].Name)
+	}
+}
+
+func TestDiscover_RespectsDepthLimit(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/": {
+				&mockDirEntry{name: "org", isDir: true},
+			},
+			"/org": {
+				&mockDirEntry{name: "project", isDir: true},
+				&mockDirEntry{name: "deep", isDir: true},
+			},
+			"/org/project": {&mockDirEntry{name: ".git", isDir: true}},
+			"/org/deep": {
+				&mockDirEntry{name: "nested", isDir: true},
+			},
+			"/org/deep/nested": {
+				&mockDirEntry{name: "project", isDir: true},
+			},
+			"/org/deep/nested/project": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Errorf("expected 1 project (depth limit), got %d", len(projects))
+	}
+}
+
+func deepTreeFileSystem() *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/src": {
+				&mockDirEntry{name: "github.com", isDir: true},
+			},
+			"/src/github.com": {
+				&mockDirEntry{name: "org", isDir: true},
+			},
+			"/src/github.com/org": {
+				&mockDirEntry{name: "team", isDir: true},
+			},
+			"/src/github.com/org/team": {
+				&mockDirEntry{name: "repo", isDir: true},
+			},
+			"/src/github.com/org/team/repo": {&mockDirEntry{name: ".git", isDir: true}},
+			"/shallow": {
+				&mockDirEntry{name: "org", isDir: true},
+			},
+			"/shallow/org": {
+				&mockDirEntry{name: "team", isDir: true},
+			},
+			"/shallow/org/team": {
+				&mockDirEntry{name: "repo", isDir: true},
+			},
+			"/shallow/org/team/repo": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+}
+
+func TestDiscover_MaxDepthOption(t *testing.T) {
+	tests := []struct {
+		maxDepth int
+		expected int
+	}{
+		{maxDepth: 2, expected: 0},
+		{maxDepth: 3, expected: 1},
+		{maxDepth: 5, expected: 1},
+	}
+
+	for _, tt := range tests {
+		result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src"}, Options{MaxDepth: mo.Some(tt.maxDepth)})
+		if result.IsError() {
+			t.Fatalf("unexpected error: %v", result.Error())
+		}
+		if got := len(result.MustGet()); got != tt.expected {
+			t.Errorf("max depth %d: expected %d projects, got %d", tt.maxDepth, tt.expected, got)
+		}
+	}
+}
+
+func TestDiscover_PerSearchPathDepth(t *testing.T) {
+	result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src:3", "/shallow:1"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Path != "/src/github.com/org/team/repo" {
+		t.Errorf("expected '/src/github.com/org/team/repo', got %q", projects[0].Path)
+	}
+}
+
+func TestDiscover_PerSearchPathDepthOverridesOption(t *testing.T) {
+	result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src:3", "/shallow"}, Options{MaxDepth: mo.Some(0)})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Path != "/src/github.com/org/team/repo" {
+		t.Errorf("expected '/src/github.com/org/team/repo', got %q", projects[0].Path)
+	}
+}
+
+func TestDiscover_MaxDepthFromEnv(t *testing.T) {
+	t.Setenv("DEV_MAX_DEPTH", "3")
+
+	result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src", "/shallow"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	if got := len(result.MustGet()); got != 2 {
+		t.Errorf("expected 2 projects, got %d", got)
+	}
+}
+
+func TestDiscover_MaxDepthOptionOverridesEnv(t *testing.T) {
+	t.Setenv("DEV_MAX_DEPTH", "3")
+
+	result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src"}, Options{MaxDepth: mo.Some(2)})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	if got := len(result.MustGet()); got != 0 {
+		t.Errorf("expected 0 projects, got %d", got)
+	}
+}
+
+func TestDiscover_InvalidMaxDepthEnv(t *testing.T) {
+	t.Setenv("DEV_MAX_DEPTH", "deep")
+
+	result := Discover(context.Background(), deepTreeFileSystem(), []string{"/src"}, Options{})
+	if result.IsOk() {
+		t.Fatal("expected error, got ok")
+	}
+}
+
+func TestParseSearchPaths_DepthSuffix(t *testing.T) {
+	tests := []struct {
+		input    string
+		path     string
+		maxDepth int
+	}{
+		{"/src", "/src", DefaultMaxDepth},
+		{"/src:5", "/src", 5},
+		{"/src:0", "/src", 0},
+		{"/weird:name", "/weird:name", DefaultMaxDepth},
+		{"/src:-1", "/src:-1", DefaultMaxDepth},
+		{"/mnt/data:2024", "/mnt/data:2024", DefaultMaxDepth},
+		{"/mnt/data:2024:3", "/mnt/data:2024", 3},
+	}
+
+	fs := &mockFileSystem{dirs: map[string][]os.DirEntry{"/mnt/data:2024": {}}}
+	for _, tt := range tests {
+		got := parseSearchPaths(fs, []string{tt.input}, DefaultMaxDepth)[0]
+		if got.path != tt.path || got.maxDepth != tt.maxDepth {
+			t.Errorf("parseSearchPaths(%q): expected {%q %d}, got {%q %d}",
+				tt.input, tt.path, tt.maxDepth, got.path, got.maxDepth)
+		}
+	}
+}
+
+func TestDiscover_DeduplicatesProjects(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "project", isDir: true},
+			},
+			"/home/user/project": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user", "/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Errorf("expected 1 project (deduplicated), got %d", len(projects))
+	}
+}
+
+func TestDiscover_ReturnsEmptyForEmptyPaths(t *testing.T) {
+	fs := &mockFileSystem{}
+	result := Discover(context.Background(), fs, []string{}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 0 {
+		t.Errorf("expected 0 projects, got %d", len(projects))
+	}
+}
+
+func TestDiscover_ReturnsEmptyForNoMatches(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "not-a-project", isDir: true},
+			},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 0 {
+		t.Errorf("expected 0 projects, got %d", len(projects))
+	}
+}
+
+func TestDiscover_ExtractsProjectNameFromPath(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "my-project", isDir: true},
+			},
+			"/home/user/my-project": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Name != "my-project" {
+		t.Errorf("expected name 'my-project', got %q", projects[0].Name)
+	}
+	if projects[0].Path != "/home/user/my-project" {
+		t.Errorf("expected path '/home/user/my-project', got %q", projects[0].Path)
+	}
+}
+
+func TestDiscover_HandlesMultipleSearchPaths(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/root1": {
+				&mockDirEntry{name: "project-a", isDir: true},
+			},
+			"/root1/project-a": {&mockDirEntry{name: ".git", isDir: true}},
+			"/root2": {
+				&mockDirEntry{name: "project-b", isDir: true},
+			},
+			"/root2/project-b": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/root1", "/root2"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 2 {
+		t.Errorf("expected 2 projects, got %d", len(projects))
+	}
+}
+
+func TestDiscover_SkipsHiddenDirectories(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: ".hidden", isDir: true},
+				&mockDirEntry{name: "visible-project", isDir: true},
+			},
+			"/home/user/.hidden": {
+				&mockDirEntry{name: "secret-project", isDir: true},
+			},
+			"/home/user/.hidden/secret-project": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/visible-project":        {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Errorf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Name != "visible-project" {
+		t.Errorf("expected 'visible-project', got %q", projects[0].Name)
+	}
+}
+
+func TestDiscover_HandlesNonExistentPath(t *testing.T) {
+	fs := &mockFileSystem{}
+	result := Discover(context.Background(), fs, []string{"/nonexistent/path/that/does/not/exist"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 0 {
+		t.Errorf("expected 0 projects, got %d", len(projects))
+	}
+}
+
+func TestDiscover_ReturnsError(t *testing.T) {
+	fs := &mockFileSystem{
+		readErr: errors.New("read error"),
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsOk() {
+		t.Fatal("expected error, got ok")
+	}
+	err := result.Error()
+	if err.Error() != "read error" {
+		t.Errorf("expected 'read error', got %q", err.Error())
+	}
+}
+
+func TestDiscover_DetectsLinkedWorktrees(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "app", isDir: true},
+				&mockDirEntry{name: "app-feature", isDir: true},
+			},
+			"/home/user/app":         {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/app-feature": {&mockDirEntry{name: ".git", isDir: false}},
+		},
+		files: map[string]string{
+			"/home/user/app-feature/.git": "gitdir: /home/user/app/.git/worktrees/app-feature\n",
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 2 {
+		t.Fatalf("expected 2 projects, got %d", len(projects))
+	}
+	for _, p := range projects {
+		switch p.Path {
+		case "/home/user/app":
+			if p.MainRepo != "" {
+				t.Errorf("expected no main repo for %q, got %q", p.Path, p.MainRepo)
+			}
+		case "/home/user/app-feature":
+			if p.MainRepo != "/home/user/app" {
+				t.Errorf("expected main repo '/home/user/app', got %q", p.MainRepo)
+			}
+		default:
+			t.Errorf("unexpected project %q", p.Path)
+		}
+	}
+}
+
+func TestDiscover_DetectsSubmoduleWithRelativeGitDir(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user/super": {
+				&mockDirEntry{name: "lib", isDir: true},
+			},
+			"/home/user/super/lib": {&mockDirEntry{name: ".git", isDir: false}},
+		},
+		files: map[string]string{
+			"/home/user/super/lib/.git": "gitdir: ../.git/modules/lib",
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user/super"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].MainRepo != "/home/user/super" {
+		t.Errorf("expected main repo '/home/user/super', got %q", projects[0].MainRepo)
+	}
+}
+
+func bareRepoFileSystem() *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/srv": {
+				&mockDirEntry{name: "mirror.git", isDir: true},
+				&mockDirEntry{name: "notes", isDir: true},
+			},
+			"/srv/mirror.git": {
+				&mockDirEntry{name: "HEAD"},
+				&mockDirEntry{name: "config"},
+				&mockDirEntry{name: "main", isDir: true},
+				&mockDirEntry{name: "objects", isDir: true},
+				&mockDirEntry{name: "refs", isDir: true},
+				&mockDirEntry{name: "worktrees", isDir: true},
+			},
+			"/srv/mirror.git/main":    {&mockDirEntry{name: ".git"}},
+			"/srv/mirror.git/objects": {},
+			"/srv/mirror.git/refs":    {},
+			"/srv/mirror.git/worktrees": {
+				&mockDirEntry{name: "feature", isDir: true},
+				&mockDirEntry{name: "main", isDir: true},
+				&mockDirEntry{name: "pruned", isDir: true},
+			},
+			"/srv/mirror.git/worktrees/feature": {},
+			"/srv/mirror.git/worktrees/main":    {},
+			"/srv/mirror.git/worktrees/pruned":  {},
+			"/work/feature":                     {&mockDirEntry{name: ".git"}},
+			// A HEAD file alone does not make a repository.
+			"/srv/notes": {&mockDirEntry{name: "HEAD"}},
+		},
+		files: map[string]string{
+			"/srv/mirror.git/HEAD":                     "ref: refs/heads/main\n",
+			"/srv/mirror.git/main/.git":                "gitdir: /srv/mirror.git/worktrees/main\n",
+			"/srv/mirror.git/worktrees/feature/gitdir": "/work/feature/.git\n",
+			"/srv/mirror.git/worktrees/main/gitdir":    "../../main/.git\n",
+			"/srv/mirror.git/worktrees/pruned/gitdir":  "/gone/pruned/.git\n",
+			"/srv/notes/HEAD":                          "ref: refs/heads/main\n",
+		},
+	}
+}
+
+func TestDiscover_DetectsBareRepositoriesWithWorktrees(t *testing.T) {
+	scan := Stream(context.Background(), bareRepoFileSystem(), []string{"/srv"}, Options{}).MustGet()
+	report := scan.Report()
+
+	if len(report.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", report.Errors)
+	}
+
+	got := lo.SliceToMap(report.Projects, func(p Project) (string, Project) { return p.Path, p })
+	if paths := projectPaths(report.Projects); !slices.Equal(paths, []string{"/srv/mirror.git", "/srv/mirror.git/main", "/work/feature"}) {
+		t.Fatalf("expected the bare repository and its worktrees, got %v", paths)
+	}
+
+	bare := got["/srv/mirror.git"]
+	if !bare.Bare || bare.Name != "mirror" || bare.VCS != VCSGit {
+		t.Errorf("expected bare git project named mirror, got %+v", bare)
+	}
+	for _, path := range []string{"/srv/mirror.git/main", "/work/feature"} {
+		if wt := got[path]; wt.Bare || wt.MainRepo != "/srv/mirror.git" {
+			t.Errorf("expected %s to be a worktree of /srv/mirror.git, got %+v", path, wt)
+		}
+	}
+}
+
+func TestGroupWorktrees(t *testing.T) {
+	list := []Project{
+		{Path: "/work/feature", MainRepo: "/srv/mirror.git"},
+		{Path: "/home/app"},
+		{Path: "/home/app-wt", MainRepo: "/home/app"},
+		{Path: "/srv/mirror.git", Bare: true},
+		{Path: "/srv/mirror.git/main", MainRepo: "/srv/mirror.git"},
+		{Path: "/srv/other.git", Bare: true},
+	}
+
+	got := lo.Map(GroupWorktrees(list), func(p Project, _ int) string { return p.Path })
+	want := []string{"/home/app", "/home/app-wt", "/srv/mirror.git", "/work/feature", "/srv/mirror.git/main", "/srv/other.git"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	const commit = "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/r/loose/.git":    {},
+			"/r/packed/.git":   {},
+			"/r/detached/.git": {},
+			"/r/unborn/.git":   {},
+			"/r/main/.git":     {},
+			"/r/wt":            {},
+			"/r/bare.git":      {},
+		},
+		files: map[string]string{
+			"/r/loose/.git/HEAD":                  "ref: refs/heads/main\n",
+			"/r/loose/.git/refs/heads/main":       commit + "\n",
+			"/r/packed/.git/HEAD":                 "ref: refs/heads/release/1.0\n",
+			"/r/packed/.git/packed-refs":          "# pack-refs with: peeled fully-peeled sorted\n" + commit + " refs/heads/release/1.0\n^ffffffffffffffffffffffffffffffffffffffff\n",
+			"/r/detached/.git/HEAD":               commit + "\n",
+			"/r/unborn/.git/HEAD":                 "ref: refs/heads/main\n",
+			"/r/main/.git/HEAD":                   "ref: refs/heads/main\n",
+			"/r/main/.git/refs/heads/feature":     commit,
+			"/r/main/.git/worktrees/wt/HEAD":      "ref: refs/heads/feature\n",
+			"/r/main/.git/worktrees/wt/commondir": "../..\n",
+			"/r/wt/.git":                          "gitdir: /r/main/.git/worktrees/wt\n",
+			"/r/bare.git/HEAD":                    "ref: refs/heads/trunk\n",
+			"/r/bare.git/refs/heads/trunk":        commit,
+		},
+	}
+
+	tests := []struct {
+		project Project
+		want    Head
+	}{
+		{Project{Path: "/r/loose"}, Head{Branch: "main", Commit: "1a2b3c4"}},
+		{Project{Path: "/r/packed"}, Head{Branch: "release/1.0", Commit: "1a2b3c4"}},
+		{Project{Path: "/r/detached"}, Head{Commit: "1a2b3c4", Detached: true}},
+		{Project{Path: "/r/unborn"}, Head{Branch: "main"}},
+		{Project{Path: "/r/wt"}, Head{Branch: "feature", Commit: "1a2b3c4"}},
+		{Project{Path: "/r/bare.git", Bare: true}, Head{Branch: "trunk", Commit: "1a2b3c4"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ReadHead(fs, tt.project).Get()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.project.Path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.project.Path, tt.want, got)
+		}
+	}
+
+	if ReadHead(fs, Project{Path: "/r/missing"}).IsOk() {
+		t.Error("expected an error for a project without .git")
+	}
+}
+
+func TestDiscover_ReadGitInfoOption(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user":          {&mockDirEntry{name: "app", isDir: true}},
+			"/home/user/app":      {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/app/.git": {},
+		},
+		files: map[string]string{
+			"/home/user/app/.git/HEAD": "ref: refs/heads/dev\n",
+		},
+	}
+
+	without := Discover(context.Background(), fs, []string{"/home/user"}, Options{}).MustGet()
+	if without[0].Head != (Head{}) {
+		t.Errorf("expected no head without ReadGitInfo, got %+v", without[0].Head)
+	}
+
+	with := Discover(context.Background(), fs, []string{"/home/user"}, Options{ReadGitInfo: true}).MustGet()
+	if with[0].Head.Branch != "dev" {
+		t.Errorf("expected branch dev, got %+v", with[0].Head)
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want Status
+	}{
+		{"clean", "# branch.oid 1a2b\n# branch.head main\n", Status{}},
+		{"ahead and behind", "# branch.head main\n# branch.upstream origin/main\n# branch.ab +2 -3\n", Status{Ahead: 2, Behind: 3}},
+		{"modified", "# branch.ab +0 -0\n1 .M N... 100644 100644 100644 1a 1a go.mod\n", Status{Dirty: true}},
+		{"untracked", "? notes.txt\n", Status{Dirty: true}},
+		{"ignored only", "! build/\n", Status{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseGitStatus([]byte(tt.out)); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestStatusCollector_ComputesEachGitProjectOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	status := func(_ context.Context, p Project) mo.Result[Status] {
+		mu.Lock()
+		calls[p.Path]++
+		mu.Unlock()
+		if p.Path == "/src/broken" {
+			return mo.Err[Status](errors.New("not a git repository"))
+		}
+		return mo.Ok(Status{Dirty: p.Path == "/src/dirty"})
+	}
+
+	c := NewStatusCollector(ctx, status, 2)
+	list := []Project{
+		{Path: "/src/clean", VCS: VCSGit},
+		{Path: "/src/dirty", VCS: VCSGit},
+		{Path: "/src/broken", VCS: VCSGit},
+		{Path: "/src/mirror.git", VCS: VCSGit, Bare: true},
+		{Path: "/src/hg", VCS: VCSMercurial},
+	}
+	c.Request(list)
+	c.Request(list)
+
+	got := make(map[string]Status)
+	for range 2 {
+		select {
+		case u := <-c.Updates():
+			got[u.Path] = u.Value
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for statuses, got %v", got)
+		}
+	}
+
+	if !got["/src/dirty"].Dirty || got["/src/clean"].Dirty {
+		t.Errorf("unexpected statuses %v", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for path, n := range calls {
+		if n != 1 {
+			t.Errorf("expected %s to be computed once, got %d", path, n)
+		}
+	}
+	if _, ok := calls["/src/hg"]; ok {
+		t.Error("expected non-git projects to be skipped")
+	}
+	if _, ok := calls["/src/mirror.git"]; ok {
+		t.Error("expected bare repositories to be skipped")
+	}
+}
+
+func TestRemoteIdentity(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"git@github.com:acme/api.git", "github.com/acme/api"},
+		{"https://github.com/acme/api", "github.com/acme/api"},
+		{"https://user@GitHub.com/acme/api.git/", "github.com/acme/api"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/infra.git", "gitlab.example.com/group/sub/infra"},
+		{"example.org:globex/api", "example.org/globex/api"},
+		{"/srv/git/api.git", ""},
+		{"../api", ""},
+		{"file:///srv/git/api.git", ""},
+		{`C:\repos\api`, ""},
+		{"https://example.org/api", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RemoteIdentity(tt.url); got != tt.want {
+			t.Errorf("RemoteIdentity(%q) = %q, expected %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestReadRemote_PrefersOrigin(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/r/api/.git":   {},
+			"/r/fork/.git":  {},
+			"/r/local/.git": {},
+		},
+		files: map[string]string{
+			"/r/api/.git/config": `[core]
+	bare = false
+[remote "upstream"]
+	url = git@github.com:globex/api.git
+[remote "origin"]
+	url = https://github.com/acme/api.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+`,
+			"/r/fork/.git/config":  "[remote \"fork\"]\n\turl = git@github.com:globex/api.git\n",
+			"/r/local/.git/config": "[core]\n\tbare = false\n",
+		},
+	}
+
+	if got := ReadRemote(fs, Project{Path: "/r/api"}).OrEmpty(); got != "github.com/acme/api" {
+		t.Errorf("expected the origin identity, got %q", got)
+	}
+	if got := ReadRemote(fs, Project{Path: "/r/fork"}).OrEmpty(); got != "github.com/globex/api" {
+		t.Errorf("expected the first remote without an origin, got %q", got)
+	}
+	if ReadRemote(fs, Project{Path: "/r/local"}).IsOk() {
+		t.Error("expected an error for a repository without remotes")
+	}
+}
+
+func TestFilter_MatchesByRemote(t *testing.T) {
+	list := []Project{
+		{Name: "api", Path: "/src/a/api", Remote: "github.com/acme/api"},
+		{Name: "api", Path: "/src/b/api", Remote: "github.com/globex/api"},
+	}
+
+	got := Filter(list, "globex/api", nil)
+	if len(got) != 1 || got[0].Path != "/src/b/api" {
+		t.Errorf("expected only globex/api, got %v", got)
+	}
+}
+
+func TestDiscover_IgnoresInvalidGitFile(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "broken", isDir: true},
+			},
+			"/home/user/broken": {&mockDirEntry{name: ".git", isDir: false}},
+		},
+		files: map[string]string{
+			"/home/user/broken/.git": "not a gitfile",
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsOk() {
+		t.Fatalf("expected error, got %d projects", len(result.MustGet()))
+	}
+}
+
+func TestDiscover_DetectsOtherVCS(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "fossil", isDir: true},
+				&mockDirEntry{name: "git", isDir: true},
+				&mockDirEntry{name: "hg", isDir: true},
+				&mockDirEntry{name: "jj", isDir: true},
+				&mockDirEntry{name: "jj-colocated", isDir: true},
+				&mockDirEntry{name: "pijul", isDir: true},
+				&mockDirEntry{name: "svn", isDir: true},
+			},
+			"/home/user/fossil": {&mockDirEntry{name: ".fslckout", isDir: false}},
+			"/home/user/git":    {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/hg":     {&mockDirEntry{name: ".hg", isDir: true}},
+			"/home/user/jj":     {&mockDirEntry{name: ".jj", isDir: true}},
+			"/home/user/jj-colocated": {
+				&mockDirEntry{name: ".git", isDir: true},
+				&mockDirEntry{name: ".jj", isDir: true},
+			},
+			"/home/user/pijul": {&mockDirEntry{name: ".pijul", isDir: true}},
+			"/home/user/svn":   {&mockDirEntry{name: ".svn", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	expected := map[string]VCS{
+		"fossil":       VCSFossil,
+		"git":          VCSGit,
+		"hg":           VCSMercurial,
+		"jj":           VCSJujutsu,
+		"jj-colocated": VCSJujutsu,
+		"pijul":        VCSPijul,
+		"svn":          VCSSubversion,
+	}
+
+	projects := result.MustGet()
+	if len(projects) != len(expected) {
+		t.Fatalf("expected %d projects, got %d", len(expected), len(projects))
+	}
+	for _, p := range projects {
+		if p.VCS != expected[p.Name] {
+			t.Errorf("%s: expected VCS %q, got %q", p.Name, expected[p.Name], p.VCS)
+		}
+	}
+}
+
+func TestDiscover_UsesCustomDetectors(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "git", isDir: true},
+				&mockDirEntry{name: "hg", isDir: true},
+			},
+			"/home/user/git": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/hg":  {&mockDirEntry{name: ".hg", isDir: true}},
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{
+		Detectors: []Detector{MarkerDetector{VCS: VCSMercurial, Names: []string{".hg"}}},
+	})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	projects := result.MustGet()
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if projects[0].Name != "hg" {
+		t.Errorf("expected 'hg', got %q", projects[0].Name)
+	}
+}
+
+func markerFileSystem() *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "scratch", isDir: true},
+				&mockDirEntry{name: "dotnet", isDir: true},
+				&mockDirEntry{name: "monorepo", isDir: true},
+				&mockDirEntry{name: "plain", isDir: true},
+			},
+			"/home/user/scratch": {&mockDirEntry{name: "go.mod", isDir: false}},
+			"/home/user/dotnet":  {&mockDirEntry{name: "App.csproj", isDir: false}},
+			"/home/user/monorepo": {
+				&mockDirEntry{name: ".git", isDir: true},
+				&mockDirEntry{name: "services", isDir: true},
+			},
+			"/home/user/monorepo/services": {
+				&mockDirEntry{name: "api", isDir: true},
+			},
+			"/home/user/monorepo/services/api": {&mockDirEntry{name: "package.json", isDir: false}},
+			"/home/user/plain":                 {&mockDirEntry{name: "notes.txt", isDir: false}},
+		},
+	}
+}
+
+func projectPaths(projects []Project) []string {
+	paths := make([]string, len(projects))
+	for i, p := range projects {
+		paths[i] = p.Path
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestDiscover_DetectsMarkerFiles(t *testing.T) {
+	result := Discover(context.Background(), markerFileSystem(), []string{"/home/user"}, Options{
+		Markers: []string{"go.mod", "package.json", "*.csproj"},
+	})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	got := projectPaths(result.MustGet())
+	expected := []string{"/home/user/dotnet", "/home/user/monorepo", "/home/user/scratch"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiscover_MarkersFromEnv(t *testing.T) {
+	t.Setenv("DEV_MARKERS", "go.mod")
+
+	result := Discover(context.Background(), markerFileSystem(), []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	got := projectPaths(result.MustGet())
+	expected := []string{"/home/user/monorepo", "/home/user/scratch"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiscover_InvalidMarkerPattern(t *testing.T) {
+	result := Discover(context.Background(), markerFileSystem(), []string{"/home/user"}, Options{
+		Markers: []string{"[go.mod"},
+	})
+	if result.IsOk() {
+		t.Fatal("expected error, got ok")
+	}
+}
+
+func TestDiscover_NestedFindsSubProjectsInsideRepos(t *testing.T) {
+	result := Discover(context.Background(), markerFileSystem(), []string{"/home/user"}, Options{
+		Markers: []string{"package.json"},
+		Nested:  true,
+	})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	got := projectPaths(result.MustGet())
+	expected := []string{"/home/user/monorepo", "/home/user/monorepo/services/api"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func workspaceFileSystem(manifest, content string) *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/repos": {
+				&mockDirEntry{name: "mono", isDir: true},
+			},
+			"/repos/mono": {
+				&mockDirEntry{name: ".git", isDir: true},
+				&mockDirEntry{name: manifest, isDir: false},
+				&mockDirEntry{name: "apps", isDir: true},
+				&mockDirEntry{name: "packages", isDir: true},
+			},
+			"/repos/mono/apps": {
+				&mockDirEntry{name: "web", isDir: true},
+				&mockDirEntry{name: "README.md", isDir: false},
+			},
+			"/repos/mono/packages": {
+				&mockDirEntry{name: "core", isDir: true},
+				&mockDirEntry{name: "internal", isDir: true},
+			},
+			"/repos/mono/packages/core":     {},
+			"/repos/mono/packages/internal": {},
+			"/repos/mono/apps/web":          {},
+		},
+		files: map[string]string{
+			"/repos/mono/" + manifest: content,
+		},
+	}
+}
+
+func TestDiscover_EmitsWorkspaceMembers(t *testing.T) {
+	tests := []struct {
+		manifest string
+		content  string
+		expected []string
+	}{
+		{
+			manifest: "go.work",
+			content:  "go 1.26\n\nuse (\n\t./apps/web\n\t./packages/core // shared\n)\nuse ./packages/internal\n",
+			expected: []string{"/repos/mono/apps/web", "/repos/mono/packages/core", "/repos/mono/packages/internal"},
+		},
+		{
+			manifest: "pnpm-workspace.yaml",
+			content:  "packages:\n  - 'apps/*'\n  - \"packages/*\"\n  - '!packages/internal'\ncatalog:\n  - ignored/*\n",
+			expected: []string{"/repos/mono/apps/web", "/repos/mono/packages/core"},
+		},
+		{
+			manifest: "package.json",
+			content:  `{"name": "mono", "workspaces": ["apps/*", "packages/core"]}`,
+			expected: []string{"/repos/mono/apps/web", "/repos/mono/packages/core"},
+		},
+		{
+			manifest: "package.json",
+			content:  `{"workspaces": {"packages": ["packages/*"]}}`,
+			expected: []string{"/repos/mono/packages/core", "/repos/mono/packages/internal"},
+		},
+		{
+			manifest: "Cargo.toml",
+			content:  "[workspace]\nmembers = [\n  \"packages/*\", # crates\n  \"apps/web\",\n]\nexclude = [\"packages/internal\"]\n\n[workspace.dependencies]\nmembers = [\"nope\"]\n",
+			expected: []string{"/repos/mono/apps/web", "/repos/mono/packages/core"},
+		},
+		{
+			manifest: "nx.json",
+			content:  `{}`,
+			expected: []string{"/repos/mono/apps/web", "/repos/mono/packages/core", "/repos/mono/packages/internal"},
+		},
+		{
+			manifest: "package.json",
+			content:  `{"workspaces": ["**"]}`,
+			expected: []string{
+				"/repos/mono/apps", "/repos/mono/apps/web",
+				"/repos/mono/packages", "/repos/mono/packages/core", "/repos/mono/packages/internal",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		result := Discover(context.Background(), workspaceFileSystem(tt.manifest, tt.content), []string{"/repos"}, Options{})
+		if result.IsError() {
+			t.Fatalf("%s: unexpected error: %v", tt.manifest, result.Error())
+		}
+
+		var members []string
+		for _, p := range result.MustGet() {
+			if p.Path == "/repos/mono" {
+				continue
+			}
+			if p.Parent != "/repos/mono" {
+				t.Errorf("%s: expected parent '/repos/mono' for %q, got %q", tt.manifest, p.Path, p.Parent)
+			}
+			members = append(members, p.Path)
+		}
+		sort.Strings(members)
+
+		if !slices.Equal(members, tt.expected) {
+			t.Errorf("%s: expected members %v, got %v", tt.manifest, tt.expected, members)
+		}
+	}
+}
+
+func TestWorkspaceMembers_StableOrderWithSeveralManifests(t *testing.T) {
+	fs := workspaceFileSystem("nx.json", `{}`)
+	fs.files["/repos/mono/turbo.json"] = `{}`
+	fs.dirs["/repos/mono"] = append(fs.dirs["/repos/mono"],
+		&mockDirEntry{name: "turbo.json"},
+		&mockDirEntry{name: "libs", isDir: true},
+	)
+	fs.dirs["/repos/mono/libs"] = []os.DirEntry{&mockDirEntry{name: "ui", isDir: true}}
+	fs.dirs["/repos/mono/libs/ui"] = nil
+
+	parent := Project{Name: "mono", Path: "/repos/mono"}
+	expected := []string{"/repos/mono/apps/web", "/repos/mono/libs/ui", "/repos/mono/packages/core", "/repos/mono/packages/internal"}
+	for range 20 {
+		members, errs := workspaceMembers(fs, parent, fs.dirs["/repos/mono"])
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		got := lo.Map(members, func(p Project, _ int) string { return p.Path })
+		if !slices.Equal(got, expected) {
+			t.Fatalf("expected members %v in order, got %v", expected, got)
+		}
+	}
+}
+
+func TestDiscover_SkipWorkspaces(t *testing.T) {
+	fs := workspaceFileSystem("package.json", `{"workspaces": ["apps/*"]}`)
+	result := Discover(context.Background(), fs, []string{"/repos"}, Options{SkipWorkspaces: true})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	got := projectPaths(result.MustGet())
+	if !slices.Equal(got, []string{"/repos/mono"}) {
+		t.Errorf("expected only the workspace root, got %v", got)
+	}
+}
+
+func TestDiscover_ReportsMalformedWorkspaceManifest(t *testing.T) {
+	fs := workspaceFileSystem("package.json", `{"workspaces": `)
+	result := Discover(context.Background(), fs, []string{"/repos"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	got := projectPaths(result.MustGet())
+	if !slices.Equal(got, []string{"/repos/mono"}) {
+		t.Errorf("expected only the workspace root, got %v", got)
+	}
+}
+
+func TestDiscover_MalformedManifestKeepsMembersOfOthers(t *testing.T) {
+	fs := workspaceFileSystem("package.json", `{"workspaces": `)
+	fs.files["/repos/mono/go.work"] = "go 1.22\n\nuse ./apps/web\n"
+	fs.dirs["/repos/mono"] = append(fs.dirs["/repos/mono"], &mockDirEntry{name: "go.work"})
+
+	report := Stream(context.Background(), fs, []string{"/repos"}, Options{}).MustGet().Report()
+
+	got := projectPaths(report.Projects)
+	if want := []string{"/repos/mono", "/repos/mono/apps/web"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Path != "/repos/mono/package.json" {
+		t.Errorf("expected one error for package.json, got %v", report.Errors)
+	}
+}
+
+func TestStream_NextReturnsProjectsAfterOffset(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "project-a", isDir: true},
+				&mockDirEntry{name: "project-b", isDir: true},
+			},
+			"/home/user/project-a": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/project-b": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	scan, err := Stream(context.Background(), fs, []string{"/home/user", "/home/user"}, Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var streamed []Project
+	for {
+		found, done := scan.Next(len(streamed))
+		streamed = append(streamed, found...)
+		if done {
+			break
+		}
+	}
+
+	all, errs := scan.Wait()
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	if len(streamed) != 2 {
+		t.Errorf("expected 2 streamed projects, got %d", len(streamed))
+	}
+	if !slices.Equal(streamed, all) {
+		t.Errorf("expected streamed projects %v to equal final result %v", streamed, all)
+	}
+
+	if found, done := scan.Next(len(all)); len(found) != 0 || !done {
+		t.Errorf("expected a completed scan to return nothing new, got %v (done=%v)", found, done)
+	}
+}
+
+func hangingFileSystem(t *testing.T) *mockFileSystem {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "fast", isDir: true},
+				&mockDirEntry{name: "nfs", isDir: true},
+			},
+			"/home/user/fast": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/nfs": {
+				&mockDirEntry{name: "remote", isDir: true},
+			},
+		},
+		hang:    map[string]bool{"/home/user/nfs": true, "/mnt/stuck": true},
+		release: release,
+	}
+}
+
+func TestStream_TimeoutReturnsPartialResults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	scan, err := Stream(ctx, hangingFileSystem(t), []string{"/home/user", "/mnt/stuck"}, Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found, _ := scan.Wait()
+	if got := projectPaths(found); !slices.Equal(got, []string{"/home/user/fast"}) {
+		t.Errorf("expected only the fast project, got %v", got)
+	}
+
+	expected := []string{"/home/user/nfs", "/mnt/stuck"}
+	if got := scan.TimedOut(); !slices.Equal(got, expected) {
+		t.Errorf("expected timed out roots %v, got %v", expected, got)
+	}
+}
+
+func TestStream_CompletedScanHasNoTimedOutRoots(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user":         {&mockDirEntry{name: "project", isDir: true}},
+			"/home/user/project": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+	}
+	scan, err := Stream(context.Background(), fs, []string{"/home/user"}, Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := scan.TimedOut(); len(got) != 0 {
+		t.Errorf("expected no timed out roots, got %v", got)
+	}
+}
+
+func TestScan_MergeKeepsPreviousProjectsBelowTimedOutRoots(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	scan, err := Stream(ctx, hangingFileSystem(t), []string{"/home/user"}, Options{}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := []Project{
+		{Name: "fast", Path: "/home/user/fast"},
+		{Name: "gone", Path: "/home/user/gone"},
+		{Name: "remote", Path: "/home/user/nfs/remote"},
+		{Name: "nfs-sibling", Path: "/home/user/nfs-sibling"},
+	}
+
+	got := projectPaths(scan.Merge(previous))
+	expected := []string{"/home/user/fast", "/home/user/nfs/remote"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiscover_CancelledContextStopsWalk(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := Discover(ctx, deepTreeFileSystem(), []string{"/src:5"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	if got := len(result.MustGet()); got != 0 {
+		t.Errorf("expected 0 projects from a cancelled scan, got %d", got)
+	}
+}
+
+// syntheticTree builds a tree of width directories per level, depth levels
+// deep, with a git repository in every leaf.
+func syntheticTree(width, depth int) *mockFileSystem {
+	fs := &mockFileSystem{dirs: make(map[string][]os.DirEntry)}
+
+	var build func(dir string, level int)
+	build = func(dir string, level int) {
+		if level == depth {
+			fs.dirs[dir] = []os.DirEntry{&mockDirEntry{name: ".git", isDir: true}}
+			return
+		}
+		for i := range width {
+			name := fmt.Sprintf("d%02d", i)
+			fs.dirs[dir] = append(fs.dirs[dir], &mockDirEntry{name: name, isDir: true})
+			build(dir+"/"+name, level+1)
+		}
+	}
+	build("/src", 0)
+
+	return fs
+}
+
+func TestDiscover_SameResultsAtAnyConcurrency(t *testing.T) {
+	fs := syntheticTree(4, 4)
+	want := projectPaths(Discover(context.Background(), fs, []string{"/src:4"}, Options{}).MustGet())
+	if len(want) != 256 {
+		t.Fatalf("expected 256 projects, got %d", len(want))
+	}
+
+	for _, n := range []int{1, 2, 64} {
+		got := projectPaths(Discover(context.Background(), fs, []string{"/src:4"}, Options{Concurrency: n}).MustGet())
+		if !slices.Equal(got, want) {
+			t.Errorf("concurrency %d: expected %d projects, got %d", n, len(want), len(got))
+		}
+	}
+}
+
+func TestPool_BoundsConcurrencyAndRunsSpawnedTasks(t *testing.T) {
+	const workers = 3
+	var mu sync.Mutex
+	running, peak, ran := 0, 0, 0
+
+	var spread func(level int) task
+	spread = func(level int) task {
+		return func(spawn func(task)) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			ran++
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+			if level < 3 {
+				for range 3 {
+					spawn(spread(level + 1))
+				}
+			}
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}
+	}
+
+	p := newPool(workers)
+	p.submit(spread(0))
+	p.run(context.Background())
+
+	if ran != 1+3+9+27 {
+		t.Errorf("expected 40 tasks to run, got %d", ran)
+	}
+	if peak > workers {
+		t.Errorf("expected at most %d tasks at once, got %d", workers, peak)
+	}
+	if peak < 2 {
+		t.Errorf("expected spawned tasks to be stolen by idle workers, peak was %d", peak)
+	}
+}
+
+func TestPool_StopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := 0
+
+	var forever task
+	forever = func(spawn func(task)) {
+		if ran++; ran == 10 {
+			cancel()
+		}
+		spawn(forever)
+	}
+
+	p := newPool(1)
+	p.submit(forever)
+	p.run(ctx)
+
+	if ran != 10 {
+		t.Errorf("expected the pool to stop after 10 tasks, ran %d", ran)
+	}
+}
+
+func excludeFileSystem() *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: "Library", isDir: true},
+				&mockDirEntry{name: "code", isDir: true},
+				&mockDirEntry{name: "node_modules", isDir: true},
+			},
+			"/home/user/Library": {
+				&mockDirEntry{name: "app", isDir: true},
+			},
+			"/home/user/Library/app":       {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/node_modules":      {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/code/node_modules": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/code": {
+				&mockDirEntry{name: ".devignore", isDir: false},
+				&mockDirEntry{name: "archive", isDir: true},
+				&mockDirEntry{name: "node_modules", isDir: true},
+				&mockDirEntry{name: "project", isDir: true},
+			},
+			"/home/user/code/archive": {
+				&mockDirEntry{name: ".devignore", isDir: false},
+				&mockDirEntry{name: "keep", isDir: true},
+				&mockDirEntry{name: "old", isDir: true},
+			},
+			"/home/user/code/archive/keep": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/code/archive/old":  {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/code/project":      {&mockDirEntry{name: ".git", isDir: true}},
+		},
+		files: map[string]string{
+			"/home/user/code/.devignore":         "# old stuff\narchive/*\n",
+			"/home/user/code/archive/.devignore": "!keep\n",
+		},
+	}
+}
+
+func TestDiscover_ExcludesAndDevignore(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes []string
+		env      string
+		expected []string
+	}{
+		{
+			name:     "devignore only",
+			expected: []string{"/home/user/Library/app", "/home/user/code/archive/keep", "/home/user/code/node_modules", "/home/user/code/project", "/home/user/node_modules"},
+		},
+		{
+			name:     "unanchored name",
+			excludes: []string{"node_modules"},
+			expected: []string{"/home/user/Library/app", "/home/user/code/archive/keep", "/home/user/code/project"},
+		},
+		{
+			name:     "anchored to search path",
+			excludes: []string{"/node_modules/", "Library"},
+			expected: []string{"/home/user/code/archive/keep", "/home/user/code/node_modules", "/home/user/code/project"},
+		},
+		{
+			name:     "from env",
+			env:      "node_modules Library",
+			expected: []string{"/home/user/code/archive/keep", "/home/user/code/project"},
+		},
+		{
+			name:     "globstar",
+			excludes: []string{"**/archive"},
+			expected: []string{"/home/user/Library/app", "/home/user/code/node_modules", "/home/user/code/project", "/home/user/node_modules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEV_EXCLUDE", tt.env)
+
+			result := Discover(context.Background(), excludeFileSystem(), []string{"/home/user:3"}, Options{Excludes: tt.excludes})
+			if result.IsError() {
+				t.Fatalf("unexpected error: %v", result.Error())
+			}
+			if got := projectPaths(result.MustGet()); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiscover_DevignoreAtSearchPath(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: ".devignore", isDir: false},
+				&mockDirEntry{name: "huge", isDir: true},
+				&mockDirEntry{name: "project", isDir: true},
+			},
+			"/home/user/huge":    {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/project": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+		files: map[string]string{
+			"/home/user/.devignore": "huge/\n",
+		},
+	}
+	result := Discover(context.Background(), fs, []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+	if got := projectPaths(result.MustGet()); !slices.Equal(got, []string{"/home/user/project"}) {
+		t.Errorf("expected only the project, got %v", got)
+	}
+}
+
+func TestDiscover_InvalidExcludePattern(t *testing.T) {
+	result := Discover(context.Background(), excludeFileSystem(), []string{"/home/user"}, Options{
+		Excludes: []string{"[z-a]"},
+	})
+	if result.IsOk() {
+		t.Fatal("expected error, got ok")
+	}
+}
+
+func TestIgnorer_MatchesGitignorePatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		ignored bool
+	}{
+		{"build", "/root/build", true},
+		{"build", "/root/a/b/build", true},
+		{"build", "/root/builder", false},
+		{"/build", "/root/a/build", false},
+		{"a/build", "/root/a/build", true},
+		{"a/build", "/root/x/a/build", false},
+		{"*.tmp", "/root/a/cache.tmp", true},
+		{"a/**/z", "/root/a/b/c/z", true},
+		{"a/**/z", "/root/a/z", true},
+		{"a/**", "/root/a/b", true},
+		{"dir?", "/root/dir1", true},
+		{"[!x]y", "/root/ay", true},
+		{"[!x]y", "/root/xy", false},
+		{"# comment", "/root/# comment", false},
+		{`\#hash`, "/root/#hash", true},
+	}
+
+	for _, tt := range tests {
+		ig, err := newIgnorer(nil, "/root", []string{tt.pattern})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.pattern, err)
+		}
+		if got := ig.ignored(tt.path); got != tt.ignored {
+			t.Errorf("pattern %q, path %q: expected ignored=%v, got %v", tt.pattern, tt.path, tt.ignored, got)
+		}
+	}
+}
+
+func hiddenFileSystem() *mockFileSystem {
+	return &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/home/user": {
+				&mockDirEntry{name: ".cache", isDir: true},
+				&mockDirEntry{name: ".config", isDir: true},
+				&mockDirEntry{name: ".dotfiles", isDir: true},
+				&mockDirEntry{name: ".local", isDir: true},
+			},
+			"/home/user/.cache": {
+				&mockDirEntry{name: "tool", isDir: true},
+			},
+			"/home/user/.cache/tool": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/.config": {
+				&mockDirEntry{name: "nvim", isDir: true},
+			},
+			"/home/user/.config/nvim": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/.dotfiles": {
+				&mockDirEntry{name: ".git", isDir: true},
+				&mockDirEntry{name: ".github", isDir: true},
+			},
+			"/home/user/.dotfiles/.git": {
+				&mockDirEntry{name: "modules", isDir: true},
+			},
+			"/home/user/.dotfiles/.git/modules": {&mockDirEntry{name: "package.json", isDir: false}},
+			"/home/user/.dotfiles/.github":      {&mockDirEntry{name: "package.json", isDir: false}},
+			"/home/user/.local": {
+				&mockDirEntry{name: "share", isDir: true},
+			},
+			"/home/user/.local/share": {
+				&mockDirEntry{name: "chezmoi", isDir: true},
+			},
+			"/home/user/.local/share/chezmoi": {&mockDirEntry{name: ".git", isDir: true}},
 		},
 	}
-	result := Discover(fs, []string{"/home/user"})
+}
+
+func TestDiscover_HiddenDirectoryAllowlist(t *testing.T) {
+	result := Discover(context.Background(), hiddenFileSystem(), []string{"/home/user"}, Options{
+		HiddenDirs: []string{".config", ".dot*", ".local"},
+	})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	projects := result.MustGet()
 
-	if len(projects) != 2 {
-		t.Errorf("expected 2 projects, got %d", len(projects))
+	expected := []string{"/home/user/.config/nvim", "/home/user/.dotfiles", "/home/user/.local/share/chezmoi"}
+	if got := projectPaths(result.MustGet()); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
 	}
 }
 
-func TestDiscover_IgnoresNonGitDirs(t *testing.T) {
-	fs := &mockFileSystem{
-		dirs: map[string][]os.DirEntry{
-			"/home/user": {
-				&mockDirEntry{name: "not-a-project", isDir: true},
-				&mockDirEntry{name: "real-project", isDir: true},
-			},
-			"/home/user/not-a-project": {},
-			"/home/user/real-project":  {&mockDirEntry{name: ".git", isDir: true}},
-		},
+func TestDiscover_HiddenDirectoriesFromEnv(t *testing.T) {
+	t.Setenv("DEV_HIDDEN_DIRS", ".config")
+
+	result := Discover(context.Background(), hiddenFileSystem(), []string{"/home/user"}, Options{})
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	result := Discover(fs, []string{"/home/user"})
+
+	if got := projectPaths(result.MustGet()); !slices.Equal(got, []string{"/home/user/.config/nvim"}) {
+		t.Errorf("expected only nvim, got %v", got)
+	}
+}
+
+func TestDiscover_AllHiddenSkipsVCSMetadata(t *testing.T) {
+	result := Discover(context.Background(), hiddenFileSystem(), []string{"/home/user"}, Options{
+		Hidden:  true,
+		Nested:  true,
+		Markers: []string{"package.json"},
+	})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	projects := result.MustGet()
 
-	if len(projects) != 1 {
-		t.Errorf("expected 1 project, got %d", len(projects))
+	expected := []string{
+		"/home/user/.cache/tool",
+		"/home/user/.config/nvim",
+		"/home/user/.dotfiles",
+		"/home/user/.dotfiles/.github",
+		"/home/user/.local/share/chezmoi",
 	}
-	if projects[0].Name != "real-project" {
-		t.Errorf("expected 'real-project', got %q", projects[0].Name)
+	if got := projectPaths(result.MustGet()); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
 	}
 }
 
-func TestDiscover_RespectsDepthLimit(t *testing.T) {
-	fs := &mockFileSystem{
+func symlinkFileSystem() *mockFileSystem {
+	return &mockFileSystem{
 		dirs: map[string][]os.DirEntry{
-			"/": {
-				&mockDirEntry{name: "org", isDir: true},
-			},
-			"/org": {
-				&mockDirEntry{name: "project", isDir: true},
-				&mockDirEntry{name: "deep", isDir: true},
-			},
-			"/org/project": {&mockDirEntry{name: ".git", isDir: true}},
-			"/org/deep": {
-				&mockDirEntry{name: "nested", isDir: true},
+			"/home/user": {
+				&mockDirEntry{name: "loop", isLink: true},
+				&mockDirEntry{name: "mirror", isLink: true},
+				&mockDirEntry{name: "work", isLink: true},
 			},
-			"/org/deep/nested": {
-				&mockDirEntry{name: "project", isDir: true},
+			"/mnt/data/work": {
+				&mockDirEntry{name: "api", isDir: true},
+				&mockDirEntry{name: "self", isLink: true},
 			},
-			"/org/deep/nested/project": {&mockDirEntry{name: ".git", isDir: true}},
+			"/mnt/data/work/api": {&mockDirEntry{name: ".git", isDir: true}},
+		},
+		links: map[string]string{
+			"/home/user/loop":     "/home/user",
+			"/home/user/mirror":   "/mnt/data/work",
+			"/home/user/work":     "/mnt/data/work",
+			"/mnt/data/work/self": "/mnt/data/work",
 		},
 	}
-	result := Discover(fs, []string{"/"})
+}
+
+func TestDiscover_IgnoresSymlinksByDefault(t *testing.T) {
+	result := Discover(context.Background(), symlinkFileSystem(), []string{"/home/user"}, Options{})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	projects := result.MustGet()
-
-	if len(projects) != 1 {
-		t.Errorf("expected 1 project (depth limit), got %d", len(projects))
+	if got := len(result.MustGet()); got != 0 {
+		t.Errorf("expected 0 projects, got %d", got)
 	}
 }
 
-func TestDiscover_DeduplicatesProjects(t *testing.T) {
-	fs := &mockFileSystem{
-		dirs: map[string][]os.DirEntry{
-			"/home/user": {
-				&mockDirEntry{name: "project", isDir: true},
-			},
-			"/home/user/project": {&mockDirEntry{name: ".git", isDir: true}},
-		},
-	}
-	result := Discover(fs, []string{"/home/user", "/home/user"})
+func TestDiscover_FollowsSymlinksOnceByRealPath(t *testing.T) {
+	result := Discover(context.Background(), symlinkFileSystem(), []string{"/home/user:5"}, Options{
+		FollowSymlinks: true,
+	})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
 	projects := result.MustGet()
 
 	if len(projects) != 1 {
-		t.Errorf("expected 1 project (deduplicated), got %d", len(projects))
+		t.Fatalf("expected 1 project, got %v", projectPaths(projects))
+	}
+	if projects[0].Path != "/home/user/mirror/api" && projects[0].Path != "/home/user/work/api" {
+		t.Errorf("expected the project under one of the links, got %q", projects[0].Path)
 	}
 }
 
-func TestDiscover_ReturnsEmptyForEmptyPaths(t *testing.T) {
-	fs := &mockFileSystem{}
-	result := Discover(fs, []string{})
+func TestDiscover_FollowsSymlinkedSearchPath(t *testing.T) {
+	result := Discover(context.Background(), symlinkFileSystem(), []string{"/home/user/work", "/mnt/data/work"}, Options{
+		FollowSymlinks: true,
+	})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	projects := result.MustGet()
-
-	if len(projects) != 0 {
-		t.Errorf("expected 0 projects, got %d", len(projects))
+	if got := len(result.MustGet()); got != 1 {
+		t.Errorf("expected 1 project, got %v", projectPaths(result.MustGet()))
 	}
 }
 
-func TestDiscover_ReturnsEmptyForNoMatches(t *testing.T) {
-	fs := &mockFileSystem{
-		dirs: map[string][]os.DirEntry{
-			"/home/user": {
-				&mockDirEntry{name: "not-a-project", isDir: true},
-			},
-		},
+func TestDiscover_FollowsSymlinksOnRealFileSystem(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on windows")
+	}
+
+	tmp := t.TempDir()
+	mustMkdir := func(path string) {
+		if err := os.MkdirAll(filepath.Join(tmp, path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustLink := func(target, link string) {
+		if err := os.Symlink(filepath.Join(tmp, target), filepath.Join(tmp, link)); err != nil {
+			t.Fatal(err)
+		}
 	}
-	result := Discover(fs, []string{"/home/user"})
+
+	mustMkdir("data/work/api/.git")
+	mustMkdir("home")
+	mustLink("data/work", "home/work")
+	mustLink("data/work", "home/mirror")
+	mustLink("home", "data/work/loop")
+
+	result := Discover(context.Background(), &filesystem.RealFileSystem{}, []string{filepath.Join(tmp, "home") + ":6"}, Options{
+		FollowSymlinks: true,
+	})
 	if result.IsError() {
 		t.Fatalf("unexpected error: %v", result.Error())
 	}
-	projects := result.MustGet()
-
-	if len(projects) != 0 {
-		t.Errorf("expected 0 projects, got %d", len(projects))
+	if got := result.MustGet(); len(got) != 1 {
+		t.Errorf("expected 1 project, got %v", projectPaths(got))
 	}
 }
 
-func TestDiscover_ExtractsProjectNameFromPath(t *testing.T) {
-	fs := &mockFileSystem{
+func TestScan_ReportsErrorsByPath(t *testing.T) {
+	fsys := &mockFileSystem{
 		dirs: map[string][]os.DirEntry{
 			"/home/user": {
-				&mockDirEntry{name: "my-project", isDir: true},
+				&mockDirEntry{name: "private", isDir: true},
+				&mockDirEntry{name: "project", isDir: true},
 			},
-			"/home/user/my-project": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user/project": {&mockDirEntry{name: ".git", isDir: true}},
 		},
+		denied: map[string]bool{"/home/user/private": true},
 	}
-	result := Discover(fs, []string{"/home/user"})
-	if result.IsError() {
-		t.Fatalf("unexpected error: %v", result.Error())
-	}
-	projects := result.MustGet()
 
-	if len(projects) != 1 {
-		t.Fatalf("expected 1 project, got %d", len(projects))
+	scan := Stream(context.Background(), fsys, []string{"/home/user"}, Options{}).MustGet()
+	report := scan.Report()
+
+	if got := projectPaths(report.Projects); !slices.Equal(got, []string{"/home/user/project"}) {
+		t.Errorf("expected the readable project, got %v", got)
 	}
-	if projects[0].Name != "my-project" {
-		t.Errorf("expected name 'my-project', got %q", projects[0].Name)
+	if len(report.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", report.Errors)
 	}
-	if projects[0].Path != "/home/user/my-project" {
-		t.Errorf("expected path '/home/user/my-project', got %q", projects[0].Path)
+	if report.Errors[0].Path != "/home/user/private" || !errors.Is(report.Errors[0], fs.ErrPermission) {
+		t.Errorf("expected permission error for /home/user/private, got %+v", report.Errors[0])
+	}
+	if len(report.Roots) != 1 || report.Roots[0].Path != "/home/user" {
+		t.Errorf("expected one timing for the search path /home/user, got %+v", report.Roots)
 	}
 }
 
-func TestDiscover_HandlesMultipleSearchPaths(t *testing.T) {
-	fs := &mockFileSystem{
+func TestScan_TimesEachSearchPathWithEverythingBelowIt(t *testing.T) {
+	fsys := &mockFileSystem{
 		dirs: map[string][]os.DirEntry{
-			"/root1": {
-				&mockDirEntry{name: "project-a", isDir: true},
-			},
-			"/root1/project-a": {&mockDirEntry{name: ".git", isDir: true}},
-			"/root2": {
-				&mockDirEntry{name: "project-b", isDir: true},
-			},
-			"/root2/project-b": {&mockDirEntry{name: ".git", isDir: true}},
+			"/home/user":             {&mockDirEntry{name: "src", isDir: true}},
+			"/home/user/src":         {&mockDirEntry{name: "project", isDir: true}},
+			"/home/user/src/project": {&mockDirEntry{name: ".git", isDir: true}},
+			"/srv":                   {},
 		},
+		delay: 10 * time.Millisecond,
 	}
-	result := Discover(fs, []string{"/root1", "/root2"})
-	if result.IsError() {
-		t.Fatalf("unexpected error: %v", result.Error())
+
+	report := Stream(context.Background(), fsys, []string{"/home/user", "/srv"}, Options{}).MustGet().Report()
+
+	got := lo.Map(report.Roots, func(r RootTiming, _ int) string { return r.Path })
+	if !slices.Equal(got, []string{"/home/user", "/srv"}) {
+		t.Fatalf("expected a timing per search path, got %+v", report.Roots)
 	}
-	projects := result.MustGet()
+	// Three directories are read one after another below /home/user.
+	if d := report.Roots[0].Duration; d < 30*time.Millisecond {
+		t.Errorf("expected the timing of /home/user to cover the walks below it, got %v", d)
+	}
+}
 
-	if len(projects) != 2 {
-		t.Errorf("expected 2 projects, got %d", len(projects))
+func TestScan_ReportsTimedOutDirsUnderTheirSearchPath(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	report := Stream(ctx, hangingFileSystem(t), []string{"/home/user", "/mnt/stuck"}, Options{}).MustGet().Report()
+
+	expected := []RootTiming{
+		{Path: "/home/user", TimedOut: []string{"/home/user/nfs"}},
+		{Path: "/mnt/stuck", TimedOut: []string{"/mnt/stuck"}},
+	}
+	if !slices.EqualFunc(report.Roots, expected, func(a, b RootTiming) bool {
+		return a.Path == b.Path && slices.Equal(a.TimedOut, b.TimedOut)
+	}) {
+		t.Errorf("expected %+v, got %+v", expected, report.Roots)
 	}
 }
 
-func TestDiscover_SkipsHiddenDirectories(t *testing.T) {
-	fs := &mockFileSystem{
-		dirs: map[string][]os.DirEntry{
-			"/home/user": {
-				&mockDirEntry{name: ".hidden", isDir: true},
-				&mockDirEntry{name: "visible-project", isDir: true},
-			},
-			"/home/user/.hidden": {
-				&mockDirEntry{name: "secret-project", isDir: true},
-			},
-			"/home/user/.hidden/secret-project": {&mockDirEntry{name: ".git", isDir: true}},
-			"/home/user/visible-project":        {&mockDirEntry{name: ".git", isDir: true}},
-		},
+func TestScan_ReportsSkippedDirs(t *testing.T) {
+	scan := Stream(context.Background(), excludeFileSystem(), []string{"/home/user:1"}, Options{
+		Excludes:      []string{"Library"},
+		RecordSkipped: true,
+	}).MustGet()
+
+	skipped := lo.SliceToMap(scan.Report().Skipped, func(s SkippedDir) (string, SkipReason) {
+		return s.Path, s.Reason
+	})
+
+	expected := map[string]SkipReason{
+		"/home/user/Library":           SkipExcluded,
+		"/home/user/code/archive/old":  SkipExcluded,
+		"/home/user/code/archive/keep": SkipDepth,
 	}
-	result := Discover(fs, []string{"/home/user"})
-	if result.IsError() {
-		t.Fatalf("unexpected error: %v", result.Error())
+	for path, reason := range expected {
+		if skipped[path] != reason {
+			t.Errorf("expected %s skipped as %q, got %q", path, reason, skipped[path])
+		}
 	}
-	projects := result.MustGet()
+}
 
-	if len(projects) != 1 {
-		t.Errorf("expected 1 project, got %d", len(projects))
+func TestScan_ReportsSkippedSymlinks(t *testing.T) {
+	scan := Stream(context.Background(), symlinkFileSystem(), []string{"/home/user:5"}, Options{
+		FollowSymlinks: true,
+		RecordSkipped:  true,
+	}).MustGet()
+
+	if !slices.Contains(scan.Report().Skipped, SkippedDir{Path: "/home/user/loop", Reason: SkipLinkLoop}) {
+		t.Errorf("expected the link back to /home/user to be skipped, got %v", scan.Report().Skipped)
 	}
-	if projects[0].Name != "visible-project" {
-		t.Errorf("expected 'visible-project', got %q", projects[0].Name)
+
+	scan = Stream(context.Background(), symlinkFileSystem(), []string{"/home/user"}, Options{RecordSkipped: true}).MustGet()
+	if got := len(scan.Report().Skipped); got != 3 {
+		t.Errorf("expected the 3 unfollowed links to be skipped, got %v", scan.Report().Skipped)
 	}
 }
 
-func TestDiscover_HandlesNonExistentPath(t *testing.T) {
-	fs := &mockFileSystem{}
-	result := Discover(fs, []string{"/nonexistent/path/that/does/not/exist"})
-	if result.IsError() {
-		t.Fatalf("unexpected error: %v", result.Error())
+func TestScan_SkippedDirsNotRecordedByDefault(t *testing.T) {
+	scan := Stream(context.Background(), excludeFileSystem(), []string{"/home/user"}, Options{
+		Excludes: []string{"Library"},
+	}).MustGet()
+	if got := scan.Report().Skipped; len(got) != 0 {
+		t.Errorf("expected no skipped dirs, got %v", got)
 	}
-	projects := result.MustGet()
+}
 
-	if len(projects) != 0 {
-		t.Errorf("expected 0 projects, got %d", len(projects))
+func TestMainRepoFromGitDir(t *testing.T) {
+	tests := []struct {
+		gitDir   string
+		expected string
+	}{
+		{"/repos/app/.git/worktrees/feature", "/repos/app"},
+		{"/repos/app/.git/modules/lib", "/repos/app"},
+		{"/repos/app/.git/modules/lib/modules/nested", "/repos/app"},
+		{"/mirrors/app.git/worktrees/feature", "/mirrors/app.git"},
+		{"/elsewhere/app.git", ""},
+	}
+
+	for _, tt := range tests {
+		if got := mainRepoFromGitDir(tt.gitDir); got != tt.expected {
+			t.Errorf("mainRepoFromGitDir(%q): expected %q, got %q", tt.gitDir, tt.expected, got)
+		}
 	}
 }
 
-func TestDiscover_ReturnsError(t *testing.T) {
+func TestDisplayNames(t *testing.T) {
+	list := []Project{
+		{Name: "backend", Path: "/home/me/work/backend"},
+		{Name: "backend", Path: "/home/me/personal/backend"},
+		{Name: "backend", Path: "/home/me/old/work/backend"},
+		{Name: "api", Path: "/srv/api.git", Bare: true},
+		{Name: "api", Path: "/home/me/api"},
+		{Name: "web", Path: "/home/me/web"},
+	}
+
+	want := map[string]string{
+		"/home/me/work/backend":     "me/work/backend",
+		"/home/me/personal/backend": "personal/backend",
+		"/home/me/old/work/backend": "old/work/backend",
+		"/srv/api.git":              "srv/api",
+		"/home/me/api":              "me/api",
+		"/home/me/web":              "web",
+	}
+
+	got := DisplayNames(list)
+	for path, name := range want {
+		if got[path] != filepath.FromSlash(name) {
+			t.Errorf("%s: expected %q, got %q", path, name, got[path])
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	list := []Project{
+		{Name: "web", Path: "/b/web", LastActivity: day(2)},
+		{Name: "API", Path: "/c/api", LastActivity: day(9)},
+		{Name: "docs", Path: "/a/docs"},
+		{Name: "cli", Path: "/d/cli", LastActivity: day(9)},
+	}
+
+	tests := []struct {
+		mode SortMode
+		want []string
+	}{
+		{SortScore, []string{"/b/web", "/c/api", "/a/docs", "/d/cli"}},
+		{SortRecent, []string{"/c/api", "/d/cli", "/b/web", "/a/docs"}},
+		{SortName, []string{"/c/api", "/d/cli", "/a/docs", "/b/web"}},
+		{SortPath, []string{"/a/docs", "/b/web", "/c/api", "/d/cli"}},
+	}
+
+	for _, tt := range tests {
+		got := lo.Map(Sort(list, tt.mode), func(p Project, _ int) string { return p.Path })
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.mode, tt.want, got)
+		}
+	}
+	if list[0].Path != "/b/web" {
+		t.Error("expected Sort to leave its input alone")
+	}
+}
+
+func TestParseSortMode(t *testing.T) {
+	if got := ParseSortMode("Recent").OrEmpty(); got != SortRecent {
+		t.Errorf("expected recent, got %q", got)
+	}
+	if ParseSortMode("size").IsOk() {
+		t.Error("expected an error for an unknown sort mode")
+	}
+	if got := SortPath.Next(); got != SortScore {
+		t.Errorf("expected path to cycle back to score, got %q", got)
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	older := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
 	fs := &mockFileSystem{
-		readErr: errors.New("read error"),
+		dirs: map[string][]os.DirEntry{
+			"/r/app":      {&mockDirEntry{name: ".git", isDir: true}},
+			"/r/app/.git": {},
+			"/r/notes":    {},
+		},
+		files: map[string]string{
+			"/r/app/.git/HEAD":      "ref: refs/heads/main\n",
+			"/r/app/.git/index":     "",
+			"/r/app/.git/logs/HEAD": "",
+		},
+		modTimes: map[string]time.Time{
+			"/r/app":                older,
+			"/r/app/.git/HEAD":      older,
+			"/r/app/.git/index":     older,
+			"/r/app/.git/logs/HEAD": newer,
+			"/r/notes":              older,
+		},
 	}
-	result := Discover(fs, []string{"/home/user"})
-	if result.IsOk() {
-		t.Fatal("expected error, got ok")
+
+	if got := LastActivity(fs, Project{Path: "/r/app", VCS: VCSGit}); !got.Equal(newer) {
+		t.Errorf("expected the last reflog entry time, got %v", got)
 	}
-	err := result.Error()
-	if err.Error() != "read error" {
-		t.Errorf("expected 'read error', got %q", err.Error())
+	if got := LastActivity(fs, Project{Path: "/r/notes"}); !got.Equal(older) {
+		t.Errorf("expected the directory time, got %v", got)
+	}
+}
+
+func TestReread(t *testing.T) {
+	checkedOut := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/r/app":      {&mockDirEntry{name: ".git", isDir: true}},
+			"/r/app/.git": {},
+		},
+		files: map[string]string{
+			"/r/app/.git/HEAD":               "ref: refs/heads/feature\n",
+			"/r/app/.git/refs/heads/feature": "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d\n",
+		},
+		modTimes: map[string]time.Time{"/r/app/.git/HEAD": checkedOut},
+	}
+	cached := Project{Name: "app", Path: "/r/app", VCS: VCSGit, Head: Head{Branch: "main", Commit: "ffffff"}}
+
+	got := Reread(fs, cached, true)
+	if got.Head.Branch != "feature" || got.Head.Commit != "1a2b3c4" {
+		t.Errorf("expected the checked out branch to be read again, got %+v", got.Head)
+	}
+	if !got.LastActivity.Equal(checkedOut) {
+		t.Errorf("expected the last activity to be read again, got %v", got.LastActivity)
+	}
+
+	if got := Reread(fs, cached, false); got.Head != cached.Head {
+		t.Errorf("expected the head to be left alone without git info, got %+v", got.Head)
 	}
 }
 
@@ -293,7 +2079,7 @@ func TestFilter_EmptyQueryReturnsAll(t *testing.T) {
 		{Name: "project-b", Path: "/repos/project-b"},
 	}
 
-	result := Filter(projects, "")
+	result := Filter(projects, "", nil)
 
 	if len(result) != len(projects) {
 		t.Errorf("expected %d projects, got %d", len(projects), len(result))
@@ -307,7 +2093,7 @@ func TestFilter_MatchesByName(t *testing.T) {
 		{Name: "api", Path: "/repos/api"},
 	}
 
-	result := Filter(projects, "front")
+	result := Filter(projects, "front", nil)
 
 	if len(result) != 1 {
 		t.Errorf("expected 1 match, got %d", len(result))
@@ -317,13 +2103,30 @@ func TestFilter_MatchesByName(t *testing.T) {
 	}
 }
 
+func TestFilter_MatchesByBranchAndCommit(t *testing.T) {
+	list := []Project{
+		{Name: "api", Path: "/src/api", Head: Head{Branch: "feature/login", Commit: "1a2b3c4"}},
+		{Name: "web", Path: "/src/web", Head: Head{Branch: "main", Commit: "9f8e7d6"}},
+	}
+
+	if got := Filter(list, "login", nil); len(got) != 1 || got[0].Name != "api" {
+		t.Errorf("expected api to match its branch, got %v", got)
+	}
+	if got := Filter(list, "9f8e", nil); len(got) != 1 || got[0].Name != "web" {
+		t.Errorf("expected web to match its commit, got %v", got)
+	}
+	if got := Filter(list, "1c4", nil); len(got) != 0 {
+		t.Errorf("expected commits to match only by prefix, got %v", got)
+	}
+}
+
 func TestFilter_MatchesByPath(t *testing.T) {
 	projects := []Project{
 		{Name: "app", Path: "/home/user/repos/app"},
 		{Name: "lib", Path: "/home/user/libs/lib"},
 	}
 
-	result := Filter(projects, "libs")
+	result := Filter(projects, "libs", nil)
 
 	if len(result) != 1 {
 		t.Errorf("expected 1 match, got %d", len(result))
@@ -348,7 +2151,7 @@ func TestFilter_CaseInsensitive(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result := Filter(projects, tt.query)
+		result := Filter(projects, tt.query, nil)
 		if len(result) != 1 {
 			t.Errorf("query %q: expected 1 match, got %d", tt.query, len(result))
 		}
@@ -361,7 +2164,7 @@ func TestFilter_NoMatchesReturnsEmpty(t *testing.T) {
 		{Name: "backend", Path: "/repos/backend"},
 	}
 
-	result := Filter(projects, "nonexistent")
+	result := Filter(projects, "nonexistent", nil)
 
 	if len(result) != 0 {
 		t.Errorf("expected 0 matches, got %d", len(result))
@@ -376,7 +2179,7 @@ func TestFilter_PartialMatch(t *testing.T) {
 	partials := []string{"my", "awesome", "project", "my-awesome", "awesome-project"}
 
 	for _, query := range partials {
-		result := Filter(projects, query)
+		result := Filter(projects, query, nil)
 		if len(result) != 1 {
 			t.Errorf("query %q: expected 1 match, got %d", query, len(result))
 		}
@@ -386,7 +2189,7 @@ func TestFilter_PartialMatch(t *testing.T) {
 func TestFilter_EmptyProjectList(t *testing.T) {
 	var projects []Project
 
-	result := Filter(projects, "anything")
+	result := Filter(projects, "anything", nil)
 
 	if len(result) != 0 {
 		t.Errorf("expected empty result, got %d items", len(result))
@@ -400,7 +2203,7 @@ func TestFilter_FuzzyMatchesOutOfOrder(t *testing.T) {
 	}
 
 	// "dc" should match "dev-cli" (d...c)
-	result := Filter(projects, "dc")
+	result := Filter(projects, "dc", nil)
 
 	if len(result) != 1 {
 		t.Errorf("expected 1 match, got %d", len(result))
@@ -417,7 +2220,7 @@ func TestFilter_FuzzyRanksBetterMatchesFirst(t *testing.T) {
 		{Name: "xdevxcli", Path: "/repos/xdevxcli"},
 	}
 
-	result := Filter(projects, "devcli")
+	result := Filter(projects, "devcli", nil)
 
 	if len(result) != 2 {
 		t.Fatalf("expected 2 matches, got %d", len(result))
@@ -434,7 +2237,7 @@ func TestFilter_FuzzyPrefersWordBoundaryMatches(t *testing.T) {
 		{Name: "dev-cli", Path: "/repos/dev-cli"},
 	}
 
-	result := Filter(projects, "dev")
+	result := Filter(projects, "dev", nil)
 
 	if len(result) != 2 {
 		t.Fatalf("expected 2 matches, got %d", len(result))
@@ -452,7 +2255,7 @@ func TestFilter_MatchesNorwegianCharacters(t *testing.T) {
 		{Name: "håland", Path: "/repos/håland"},
 	}
 
-	result := Filter(projects, "øl")
+	result := Filter(projects, "øl", nil)
 	if len(result) != 1 {
 		t.Errorf("expected 1 match for 'øl', got %d", len(result))
 	}
@@ -460,7 +2263,7 @@ func TestFilter_MatchesNorwegianCharacters(t *testing.T) {
 		t.Errorf("expected 'øl-project', got %q", result[0].Name)
 	}
 
-	result = Filter(projects, "sæ")
+	result = Filter(projects, "sæ", nil)
 	if len(result) != 1 {
 		t.Errorf("expected 1 match for 'sæ', got %d", len(result))
 	}
@@ -468,7 +2271,7 @@ func TestFilter_MatchesNorwegianCharacters(t *testing.T) {
 		t.Errorf("expected 'særen', got %q", result[0].Name)
 	}
 
-	result = Filter(projects, "hå")
+	result = Filter(projects, "hå", nil)
 	if len(result) != 1 {
 		t.Errorf("expected 1 match for 'hå', got %d", len(result))
 	}
@@ -498,7 +2301,7 @@ func TestFilter_NorwegianCaseInsensitive(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result := Filter(projects, tt.query)
+		result := Filter(projects, tt.query, nil)
 		if len(result) != 1 {
 			t.Errorf("query %q: expected 1 match, got %d", tt.query, len(result))
 			continue
@@ -516,7 +2319,7 @@ func TestFilter_NorwegianFuzzyMatching(t *testing.T) {
 	}
 
 	// "fp" should match "første-prosjekt"
-	result := Filter(projects, "fp")
+	result := Filter(projects, "fp", nil)
 	if len(result) != 1 {
 		t.Errorf("expected 1 match for 'fp', got %d", len(result))
 	}
@@ -525,7 +2328,7 @@ func TestFilter_NorwegianFuzzyMatching(t *testing.T) {
 	}
 
 	// "ap" should match "anden-prosjekt"
-	result = Filter(projects, "ap")
+	result = Filter(projects, "ap", nil)
 	if len(result) != 1 {
 		t.Errorf("expected 1 match for 'ap', got %d", len(result))
 	}
@@ -541,7 +2344,7 @@ func TestFilter_NorwegianWordBoundary(t *testing.T) {
 	}
 
 	// "øl" at start should rank higher
-	result := Filter(projects, "øl")
+	result := Filter(projects, "øl", nil)
 	if len(result) != 2 {
 		t.Fatalf("expected 2 matches, got %d", len(result))
 	}
@@ -685,7 +2488,7 @@ func TestFilter_ResultIsSubsetOfInput(t *testing.T) {
 				projects = append(projects, Project{Name: name, Path: "/" + name})
 			}
 
-			result := Filter(projects, query)
+			result := Filter(projects, query, nil)
 
 			// Every result must exist in original
 			for _, r := range result {
@@ -719,7 +2522,7 @@ func TestFilter_ResultNeverLargerThanInput(t *testing.T) {
 				projects = append(projects, Project{Name: name, Path: "/" + name})
 			}
 
-			result := Filter(projects, query)
+			result := Filter(projects, query, nil)
 
 			return len(result) <= len(projects)
 		},
@@ -740,7 +2543,7 @@ func TestFilter_EmptyQueryReturnsAllProperty(t *testing.T) {
 				projects = append(projects, Project{Name: name, Path: "/" + name})
 			}
 
-			result := Filter(projects, "")
+			result := Filter(projects, "", nil)
 
 			return len(result) == len(projects)
 		},
@@ -760,8 +2563,8 @@ func TestFilter_FilteringIsIdempotent(t *testing.T) {
 				projects = append(projects, Project{Name: name, Path: "/" + name})
 			}
 
-			first := Filter(projects, query)
-			second := Filter(first, query)
+			first := Filter(projects, query, nil)
+			second := Filter(first, query, nil)
 
 			if len(first) != len(second) {
 				return false
@@ -786,3 +2589,105 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+func TestVisits_Frecency(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	visits := Visits{}
+	for range 4 {
+		visits.Record("/often", now.Add(-2*frecencyHalfLife))
+	}
+	visits.Record("/recent", now)
+
+	scores := visits.Scores(now)
+	if got := scores["/often"]; math.Abs(got-1) > 1e-9 {
+		t.Errorf("expected 4 visits two half-lives ago to score 1, got %v", got)
+	}
+	if got := scores["/recent"]; got != 1 {
+		t.Errorf("expected a visit just now to score 1, got %v", got)
+	}
+	if visits["/often"].Count != 4 || !visits["/recent"].Last.Equal(now) {
+		t.Errorf("unexpected visits %+v", visits)
+	}
+}
+
+func TestFilter_RanksTiesByFrecency(t *testing.T) {
+	list := []Project{
+		{Name: "api", Path: "/a/api"},
+		{Name: "app", Path: "/b/app"},
+		{Name: "web", Path: "/c/web"},
+	}
+	rank := map[string]float64{"/b/app": 3, "/c/web": 5}
+	paths := func(list []Project) []string {
+		return lo.Map(list, func(p Project, _ int) string { return p.Path })
+	}
+
+	if got, want := paths(Filter(list, "", rank)), []string{"/c/web", "/b/app", "/a/api"}; !slices.Equal(got, want) {
+		t.Errorf("empty query: expected %v, got %v", want, got)
+	}
+	if got, want := paths(Filter(list, "ap", rank)), []string{"/b/app", "/a/api"}; !slices.Equal(got, want) {
+		t.Errorf("tie: expected %v, got %v", want, got)
+	}
+	if got, want := paths(Filter(list, "", nil)), paths(list); !slices.Equal(got, want) {
+		t.Errorf("no rank: expected %v, got %v", want, got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	fs := &mockFileSystem{
+		dirs: map[string][]os.DirEntry{
+			"/srv/app":        {&mockDirEntry{name: ".git", isDir: true}},
+			"/opt/vendor-sdk": {&mockDirEntry{name: "README", isDir: false}},
+		},
+		denied: map[string]bool{"/root/secret": true},
+	}
+
+	app := Inspect(fs, "/srv/app").MustGet()
+	if app.Name != "app" || app.VCS != VCSGit {
+		t.Errorf("expected git project app, got %+v", app)
+	}
+	sdk := Inspect(fs, "/opt/vendor-sdk").MustGet()
+	if sdk.Name != "vendor-sdk" || sdk.VCS != "" {
+		t.Errorf("expected plain project vendor-sdk, got %+v", sdk)
+	}
+	if Inspect(fs, "/root/secret").IsOk() {
+		t.Error("expected an error for an unreadable directory")
+	}
+}
+
+func TestMergeRegistered(t *testing.T) {
+	found := []Project{
+		{Name: "api", Path: "/src/api"},
+		{Name: "web", Path: "/src/web"},
+	}
+	registered := []Project{
+		{Name: "web", Path: "/src/web", Pinned: true},
+		{Name: "app", Path: "/srv/app"},
+	}
+
+	merged := MergeRegistered(found, registered)
+	got := lo.Map(merged, func(p Project, _ int) string {
+		return fmt.Sprintf("%s %t", p.Path, p.Pinned)
+	})
+	want := []string{"/src/api false", "/src/web true", "/srv/app false"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if found[1].Pinned {
+		t.Error("expected MergeRegistered to leave its input alone")
+	}
+}
+
+func TestFilter_PinnedFirstWithoutQuery(t *testing.T) {
+	list := []Project{
+		{Name: "api", Path: "/a/api"},
+		{Name: "app", Path: "/b/app", Pinned: true},
+		{Name: "web", Path: "/c/web"},
+	}
+	rank := map[string]float64{"/c/web": 5}
+
+	got := lo.Map(Filter(list, "", rank), func(p Project, _ int) string { return p.Path })
+	want := []string{"/b/app", "/c/web", "/a/api"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,58 @@
+package projects
+
+import (
+	"path/filepath"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/mo"
+)
+
+// Inspect returns the project at dir as a scan would detect it, or one
+// without version control named after dir when no detector matches.
+func Inspect(fs filesystem.FileSystem, dir string) mo.Result[Project] {
+	entries, err := fs.ReadDir(dir).Get()
+	if err != nil {
+		return mo.Err[Project](err)
+	}
+
+	for _, d := range DefaultDetectors() {
+		for _, entry := range entries {
+			if !d.Match(entry) {
+				continue
+			}
+			if p, err := d.Project(fs, dir, entry).Get(); err == nil {
+				return mo.Ok(p)
+			}
+		}
+	}
+	return mo.Ok(Project{Name: filepath.Base(dir), Path: dir})
+}
+
+// MergeRegistered adds the registered projects that list does not have yet,
+// and marks the projects of either that are pinned in registered as such.
+func MergeRegistered(list, registered []Project) []Project {
+	if len(registered) == 0 {
+		return list
+	}
+
+	pinned := make(map[string]bool, len(registered))
+	for _, p := range registered {
+		pinned[p.Path] = p.Pinned
+	}
+
+	merged := make([]Project, 0, len(list)+len(registered))
+	for _, p := range list {
+		if isPinned, ok := pinned[p.Path]; ok {
+			p.Pinned = isPinned
+			delete(pinned, p.Path)
+		}
+		merged = append(merged, p)
+	}
+	for _, p := range registered {
+		if _, ok := pinned[p.Path]; ok {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,109 @@
+package projects
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/mo"
+)
+
+// ReadRemote returns the identity of the git project p's origin remote, or
+// of its first remote if it has no origin. Remotes that are local paths
+// have no identity and yield "".
+func ReadRemote(fs filesystem.FileSystem, p Project) mo.Result[string] {
+	gitDir, err := gitDirOf(fs, p).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+
+	data, err := fs.ReadFile(filepath.Join(commonDirOf(fs, gitDir), "config")).Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+
+	remotes, order := parseRemoteURLs(string(data))
+	if len(order) == 0 {
+		return mo.Err[string](errors.New("no remotes"))
+	}
+
+	remoteURL, ok := remotes["origin"]
+	if !ok {
+		remoteURL = remotes[order[0]]
+	}
+	return mo.Ok(RemoteIdentity(remoteURL))
+}
+
+// parseRemoteURLs reads the first url of every [remote "name"] section of a
+// git config file, and the names in the order they appear.
+func parseRemoteURLs(config string) (map[string]string, []string) {
+	urls := make(map[string]string)
+	var order []string
+	remote := ""
+
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			section := strings.TrimSpace(strings.Trim(line, "[]"))
+			if name, ok := strings.CutPrefix(section, "remote "); ok {
+				remote = strings.Trim(strings.TrimSpace(name), `"`)
+			}
+			continue
+		}
+
+		if remote == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "url") {
+			continue
+		}
+		if _, seen := urls[remote]; seen {
+			continue
+		}
+		urls[remote] = strings.Trim(strings.TrimSpace(value), `"`)
+		order = append(order, remote)
+	}
+
+	return urls, order
+}
+
+// RemoteIdentity normalizes a git remote URL to host/owner/repo, so the
+// https, ssh and scp-like forms of one repository are the same. Groups
+// nested below the owner, as GitLab has them, are kept. Local paths and
+// file URLs have no identity and yield "".
+func RemoteIdentity(remoteURL string) string {
+	var host, path string
+
+	u, err := url.Parse(remoteURL)
+	if err == nil && u.Scheme == "file" {
+		return ""
+	}
+
+	if err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if i := strings.Index(remoteURL, ":"); i > 1 && !strings.Contains(remoteURL[:i], "/") {
+		// scp-like syntax, [user@]host:owner/repo. A single letter before
+		// the colon is a Windows drive rather than a host.
+		host, path = remoteURL[:i], remoteURL[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return ""
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return ""
+	}
+	return strings.ToLower(host) + "/" + path
+}
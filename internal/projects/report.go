@@ -0,0 +1,91 @@
+package projects
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// DiscoverReport describes a completed scan: what it found, where it failed,
+// what it left out and how long each search path took.
+type DiscoverReport struct {
+	Projects []Project
+	Errors   []PathError
+	// Skipped is only filled in when Options.RecordSkipped is set.
+	Skipped  []SkippedDir
+	Roots    []RootTiming
+	TimedOut []string
+	Duration time.Duration
+}
+
+// PathError is an error the walk ran into while looking at Path.
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e PathError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PathError) Unwrap() error {
+	return e.Err
+}
+
+type SkipReason string
+
+const (
+	SkipHidden     SkipReason = "hidden"
+	SkipExcluded   SkipReason = "excluded"
+	SkipDepth      SkipReason = "below max depth"
+	SkipSymlink    SkipReason = "symlink not followed"
+	SkipLinkLoop   SkipReason = "symlink to its own parent"
+	SkipLinkRepeat SkipReason = "symlink target already walked"
+)
+
+// SkippedDir is a directory the walk did not descend into.
+type SkippedDir struct {
+	Path   string
+	Reason SkipReason
+}
+
+// RootTiming is how long the walk below one search path took, all
+// directories in it included. Paths that timed out report the time until
+// the scan gave up on them.
+type RootTiming struct {
+	Path     string
+	Duration time.Duration
+	// TimedOut lists what the scan had not finished below Path.
+	TimedOut []string
+}
+
+// Report blocks until the walk has completed and describes it. Errors,
+// skipped directories and timings are sorted by path.
+func (s *Scan) Report() DiscoverReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.done {
+		s.cond.Wait()
+	}
+
+	report := DiscoverReport{
+		Projects: slices.Clone(s.projects),
+		Errors:   slices.Clone(s.errs),
+		Skipped:  slices.Clone(s.skipped),
+		TimedOut: slices.Clone(s.timedOut),
+		Duration: s.duration,
+	}
+	for path, d := range s.timings {
+		timedOut := lo.Filter(s.timedOut, func(dir string, _ int) bool { return isWithin(dir, path) })
+		report.Roots = append(report.Roots, RootTiming{Path: path, Duration: d, TimedOut: timedOut})
+	}
+
+	slices.SortStableFunc(report.Errors, func(a, b PathError) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(report.Skipped, func(a, b SkippedDir) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(report.Roots, func(a, b RootTiming) int { return strings.Compare(a.Path, b.Path) })
+
+	return report
+}
@@ -0,0 +1,242 @@
+package projects
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Scan is a discovery running in the background. Projects are deduplicated
+// as they arrive and can be read while the walk is still going.
+type Scan struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	cond     *sync.Cond
+	projects []Project
+	errs     []PathError
+	skipped  []SkippedDir
+	pending  map[string]int
+	running  map[string]int
+	started  time.Time
+	timings  map[string]time.Duration
+	timedOut []string
+	duration time.Duration
+	done     bool
+}
+
+func newScan(ctx context.Context) *Scan {
+	s := &Scan{
+		ctx:      ctx,
+		projects: make([]Project, 0, 64),
+		pending:  make(map[string]int),
+		running:  make(map[string]int),
+		started:  time.Now(),
+		timings:  make(map[string]time.Duration),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Next blocks until more than from projects have been found or the walk has
+// completed. It returns the projects after the first from, and whether the
+// walk has completed.
+func (s *Scan) Next(from int) ([]Project, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.projects) <= from && !s.done {
+		s.cond.Wait()
+	}
+	return slices.Clip(s.projects[min(from, len(s.projects)):]), s.done
+}
+
+// Wait blocks until the walk has completed and returns everything it found
+// together with the errors it ran into.
+func (s *Scan) Wait() ([]Project, []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.done {
+		s.cond.Wait()
+	}
+
+	errs := make([]error, len(s.errs))
+	for i, e := range s.errs {
+		errs[i] = e.Err
+	}
+	return slices.Clip(s.projects), errs
+}
+
+// TimedOut blocks until the walk has completed and returns the search paths,
+// or directories right below them, it had not finished when its context
+// ended, sorted.
+func (s *Scan) TimedOut() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.done {
+		s.cond.Wait()
+	}
+	return slices.Clip(s.timedOut)
+}
+
+// Merge returns what the scan found plus those of previous that lie below a
+// search path the scan did not finish, so an interrupted scan does not
+// forget projects an earlier one found there.
+func (s *Scan) Merge(previous []Project) []Project {
+	result, _ := s.Wait()
+	timedOut := s.TimedOut()
+	if len(timedOut) == 0 {
+		return result
+	}
+
+	paths := lo.SliceToMap(result, func(p Project) (string, struct{}) {
+		return p.Path, struct{}{}
+	})
+
+	merged := slices.Clone(result)
+	for _, p := range previous {
+		if _, exists := paths[p.Path]; exists {
+			continue
+		}
+		if lo.SomeBy(timedOut, func(root string) bool { return isWithin(p.Path, root) }) {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+func isWithin(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// walkRoot is what a walk is counted towards: the search path it belongs
+// to, which is timed as a whole, and the directory right below it the walk
+// started from, which is reported if the context ends first. A walk of the
+// search path itself has it as both.
+type walkRoot struct {
+	search string
+	dir    string
+}
+
+// track and untrack count the walks running below a search path and each
+// directory right below it, so the directories still being walked can be
+// reported if the context ends first, and time how long each search path
+// took. Walks returning after the context ended were cut short, so they
+// stay counted.
+func (s *Scan) track(root walkRoot) {
+	s.mu.Lock()
+	s.pending[root.dir]++
+	s.running[root.search]++
+	s.mu.Unlock()
+}
+
+func (s *Scan) untrack(root walkRoot) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	if s.pending[root.dir]--; s.pending[root.dir] <= 0 {
+		delete(s.pending, root.dir)
+	}
+	if s.running[root.search]--; s.running[root.search] <= 0 {
+		delete(s.running, root.search)
+		if !s.done {
+			s.timings[root.search] = time.Since(s.started)
+		}
+	}
+	s.mu.Unlock()
+}
+
+func (s *Scan) skip(path string, reason SkipReason) {
+	s.mu.Lock()
+	s.skipped = append(s.skipped, SkippedDir{Path: path, Reason: reason})
+	s.mu.Unlock()
+}
+
+func (s *Scan) collect(ctx context.Context, resultCh <-chan found, errCh <-chan PathError) {
+	seen := make(map[string]struct{})
+	add := func(f found) {
+		if _, exists := seen[f.key]; exists {
+			return
+		}
+		seen[f.key] = struct{}{}
+		s.mu.Lock()
+		s.projects = append(s.projects, f.project)
+		s.mu.Unlock()
+		s.cond.Broadcast()
+	}
+	addErr := func(err PathError) {
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+	}
+
+	for resultCh != nil || errCh != nil {
+		select {
+		case <-ctx.Done():
+			s.drain(resultCh, errCh, add, addErr)
+			s.finish(true)
+			return
+
+		case f, ok := <-resultCh:
+			if !ok {
+				resultCh = nil
+				continue
+			}
+			add(f)
+
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			addErr(err)
+		}
+	}
+
+	s.finish(false)
+}
+
+// drain picks up whatever the walkers had already sent when the context
+// ended, without waiting for more.
+func (s *Scan) drain(resultCh <-chan found, errCh <-chan PathError, add func(found), addErr func(PathError)) {
+	for {
+		select {
+		case f, ok := <-resultCh:
+			if !ok {
+				resultCh = nil
+				continue
+			}
+			add(f)
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			addErr(err)
+		default:
+			return
+		}
+	}
+}
+
+func (s *Scan) finish(interrupted bool) {
+	s.mu.Lock()
+	s.duration = time.Since(s.started)
+	if interrupted {
+		s.timedOut = lo.Keys(s.pending)
+		slices.Sort(s.timedOut)
+		for path := range s.running {
+			s.timings[path] = s.duration
+		}
+	}
+	s.done = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
@@ -0,0 +1,98 @@
+package projects
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// SortMode is the order projects are listed in.
+type SortMode string
+
+const (
+	// SortScore lists the best matches of the query first, and projects in
+	// the order they are given while there is no query.
+	SortScore  SortMode = "score"
+	SortRecent SortMode = "recent"
+	SortName   SortMode = "name"
+	SortPath   SortMode = "path"
+)
+
+// SortModes are the sort modes in the order they are cycled through.
+var SortModes = []SortMode{SortScore, SortRecent, SortName, SortPath}
+
+func ParseSortMode(s string) mo.Result[SortMode] {
+	mode := SortMode(strings.ToLower(s))
+	if !slices.Contains(SortModes, mode) {
+		return mo.Err[SortMode](fmt.Errorf("invalid sort mode %q, expected one of %s", s, strings.Join(lo.Map(SortModes, func(m SortMode, _ int) string { return string(m) }), ", ")))
+	}
+	return mo.Ok(mode)
+}
+
+// Next returns the sort mode after m.
+func (m SortMode) Next() SortMode {
+	return SortModes[(slices.Index(SortModes, m)+1)%len(SortModes)]
+}
+
+// Sort returns list ordered by mode, keeping the order of projects that
+// compare equal. SortScore keeps list as it is, since Filter already orders
+// by score.
+func Sort(list []Project, mode SortMode) []Project {
+	var compare func(a, b Project) int
+	switch mode {
+	case SortRecent:
+		compare = func(a, b Project) int { return b.LastActivity.Compare(a.LastActivity) }
+	case SortName:
+		compare = func(a, b Project) int { return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+	case SortPath:
+		compare = func(a, b Project) int { return cmp.Compare(a.Path, b.Path) }
+	default:
+		return list
+	}
+
+	sorted := slices.Clone(list)
+	slices.SortStableFunc(sorted, compare)
+	return sorted
+}
+
+// LastActivity returns when p was last worked on. For git projects that is
+// when HEAD last moved, by commit, checkout or otherwise, or when files were
+// last staged. Other projects fall back to when their directory changed.
+func LastActivity(fs filesystem.FileSystem, p Project) time.Time {
+	var latest time.Time
+	if p.VCS == VCSGit {
+		if gitDir, err := gitDirOf(fs, p).Get(); err == nil {
+			for _, file := range []string{"logs/HEAD", "index", "HEAD"} {
+				if info, err := fs.Stat(filepath.Join(gitDir, filepath.FromSlash(file))).Get(); err == nil && info.ModTime().After(latest) {
+					latest = info.ModTime()
+				}
+			}
+		}
+	}
+
+	if latest.IsZero() {
+		if info, err := fs.Stat(p.Path).Get(); err == nil {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Reread returns p, listed some time ago such as from the cache, with what
+// may have changed since read in again: its last activity, and its head
+// and remote when gitInfo is set.
+func Reread(fs filesystem.FileSystem, p Project, gitInfo bool) Project {
+	if gitInfo {
+		p = withGitInfo(fs, p)
+	}
+	p.LastActivity = LastActivity(fs, p)
+	return p
+}
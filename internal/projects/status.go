@@ -0,0 +1,79 @@
+package projects
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+// statusTimeout bounds how long one repository's status may take, so a huge
+// working tree cannot hold up the others.
+const statusTimeout = 5 * time.Second
+
+// Status is the state of a project's working tree against its upstream.
+type Status struct {
+	Dirty  bool
+	Ahead  int
+	Behind int
+}
+
+// StatusFunc computes the status of one project.
+type StatusFunc func(ctx context.Context, p Project) mo.Result[Status]
+
+// GitStatus asks git for the status of a git project, which is the only
+// reliable way to tell whether the working tree matches the index.
+func GitStatus(ctx context.Context, p Project) mo.Result[Status] {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", p.Path, "--no-optional-locks",
+		"status", "--porcelain=v2", "--branch", "--untracked-files=normal")
+	out, err := cmd.Output()
+	if err != nil {
+		return mo.Err[Status](err)
+	}
+	return mo.Ok(parseGitStatus(out))
+}
+
+// parseGitStatus reads `git status --porcelain=v2 --branch` output. Headers
+// start with "#", every other line is a changed or untracked path.
+func parseGitStatus(out []byte) Status {
+	var s Status
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		switch {
+		case len(line) == 0:
+		case line[0] == '#':
+			ab, ok := strings.CutPrefix(string(line), "# branch.ab ")
+			if !ok {
+				continue
+			}
+			ahead, behind, _ := strings.Cut(ab, " ")
+			s.Ahead, _ = strconv.Atoi(strings.TrimPrefix(ahead, "+"))
+			s.Behind, _ = strconv.Atoi(strings.TrimPrefix(behind, "-"))
+		case line[0] != '!':
+			s.Dirty = true
+		}
+	}
+	return s
+}
+
+// StatusCollector computes the status of git projects in the background on
+// a few workers, so a slow repository never holds up the caller.
+type StatusCollector = Collector[Status]
+
+// StatusUpdate is the status of the project at Path.
+type StatusUpdate = Update[Status]
+
+// NewStatusCollector starts workers computing statuses with status until
+// ctx ends. Bare repositories have no working tree and are left out, like
+// projects that are not git repositories.
+func NewStatusCollector(ctx context.Context, status StatusFunc, workers int) *StatusCollector {
+	return NewCollector(ctx, status, func(p Project) bool {
+		return p.VCS == VCSGit && !p.Bare
+	}, workers)
+}
@@ -0,0 +1,315 @@
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// maxGlobstarDepth bounds how far a "**" segment in a workspace pattern
+// descends, so a careless pattern cannot turn into a full tree walk.
+const maxGlobstarDepth = 6
+
+// workspaceManifest reads member patterns from one kind of manifest.
+type workspaceManifest struct {
+	file  string
+	parse func(data []byte) mo.Result[[]string]
+}
+
+var workspaceManifests = []workspaceManifest{
+	{file: "go.work", parse: parseGoWork},
+	{file: "pnpm-workspace.yaml", parse: parsePnpmWorkspace},
+	{file: "package.json", parse: parsePackageJSONWorkspaces},
+	{file: "Cargo.toml", parse: parseCargoWorkspace},
+}
+
+// workspaceLayout is a tool that implies member directories by convention
+// rather than listing them.
+type workspaceLayout struct {
+	file     string
+	patterns []string
+}
+
+// workspaceLayouts apply only when no manifest lists members. Like the
+// manifests they are checked in order, so members come out the same way
+// on every run.
+var workspaceLayouts = []workspaceLayout{
+	{file: "nx.json", patterns: []string{"apps/*", "libs/*", "packages/*"}},
+	{file: "turbo.json", patterns: []string{"apps/*", "packages/*"}},
+}
+
+// workspaceMembers returns a project for every member directory declared by
+// the workspace manifests found among entries of parent. A manifest that
+// cannot be read or parsed is reported by its path, and the members the
+// others declare are still returned.
+func workspaceMembers(fs filesystem.FileSystem, parent Project, entries []os.DirEntry) ([]Project, []PathError) {
+	names := lo.SliceToMap(entries, func(e os.DirEntry) (string, bool) {
+		return e.Name(), !e.IsDir()
+	})
+
+	var patterns []string
+	var errs []PathError
+	for _, m := range workspaceManifests {
+		if !names[m.file] {
+			continue
+		}
+		path := filepath.Join(parent.Path, m.file)
+		data, err := fs.ReadFile(path).Get()
+		if err != nil {
+			errs = append(errs, PathError{Path: path, Err: err})
+			continue
+		}
+		found, err := m.parse(data).Get()
+		if err != nil {
+			errs = append(errs, PathError{Path: path, Err: err})
+			continue
+		}
+		patterns = append(patterns, found...)
+	}
+
+	if len(patterns) == 0 {
+		for _, l := range workspaceLayouts {
+			if names[l.file] {
+				patterns = append(patterns, l.patterns...)
+			}
+		}
+	}
+
+	if len(patterns) == 0 {
+		return []Project{}, errs
+	}
+
+	excluded := make(map[string]struct{})
+	var included []string
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			for _, dir := range expandWorkspaceGlob(fs, parent.Path, negated) {
+				excluded[dir] = struct{}{}
+			}
+			continue
+		}
+		included = append(included, expandWorkspaceGlob(fs, parent.Path, pattern)...)
+	}
+
+	members := lo.FilterMap(lo.Uniq(included), func(dir string, _ int) (Project, bool) {
+		if _, skip := excluded[dir]; skip || dir == parent.Path {
+			return Project{}, false
+		}
+		return Project{
+			Name:   filepath.Base(dir),
+			Path:   dir,
+			Parent: parent.Path,
+		}, true
+	})
+
+	return members, errs
+}
+
+// expandWorkspaceGlob resolves a slash-separated workspace pattern relative
+// to root into the existing directories it names.
+func expandWorkspaceGlob(fs filesystem.FileSystem, root, pattern string) []string {
+	pattern = strings.Trim(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	if pattern == "" || pattern == "." {
+		return []string{root}
+	}
+	return matchSegments(fs, root, strings.Split(pattern, "/"), 0)
+}
+
+func matchSegments(fs filesystem.FileSystem, dir string, segments []string, globstarDepth int) []string {
+	if len(segments) == 0 {
+		return []string{dir}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if segment == "." {
+		return matchSegments(fs, dir, rest, globstarDepth)
+	}
+	if segment == ".." {
+		return matchSegments(fs, filepath.Dir(dir), rest, globstarDepth)
+	}
+
+	entries, err := fs.ReadDir(dir).Get()
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	if segment == "**" {
+		matches = append(matches, matchSegments(fs, dir, rest, 0)...)
+		if globstarDepth >= maxGlobstarDepth {
+			return matches
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && entry.Name() != "node_modules" {
+				matches = append(matches, matchSegments(fs, filepath.Join(dir, entry.Name()), segments, globstarDepth+1)...)
+			}
+		}
+		return matches
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if ok, _ := filepath.Match(segment, entry.Name()); !ok {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(segment, ".") {
+			continue
+		}
+		matches = append(matches, matchSegments(fs, filepath.Join(dir, entry.Name()), rest, 0)...)
+	}
+	return matches
+}
+
+// parseGoWork reads the directories of "use" directives, both the single
+// line and the parenthesized block form.
+func parseGoWork(data []byte) mo.Result[[]string] {
+	var dirs []string
+	inBlock := false
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, unquote(line))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, unquote(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+		}
+	}
+
+	return mo.Ok(dirs)
+}
+
+// parsePnpmWorkspace reads the "packages" list of pnpm-workspace.yaml. Only
+// the block sequence form pnpm documents is understood.
+func parsePnpmWorkspace(data []byte) mo.Result[[]string] {
+	var patterns []string
+	inPackages := false
+
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			inPackages = trimmed == "packages:"
+			continue
+		}
+
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok && inPackages {
+			item, _, _ = strings.Cut(item, " #")
+			patterns = append(patterns, unquote(strings.TrimSpace(item)))
+		}
+	}
+
+	return mo.Ok(patterns)
+}
+
+// parsePackageJSONWorkspaces reads npm and yarn "workspaces", which is either
+// a list of patterns or an object with a "packages" list.
+func parsePackageJSONWorkspaces(data []byte) mo.Result[[]string] {
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return mo.Err[[]string](err)
+	}
+	if len(manifest.Workspaces) == 0 {
+		return mo.Ok([]string{})
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return mo.Ok(patterns)
+	}
+
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &object); err != nil {
+		return mo.Err[[]string](fmt.Errorf("workspaces: %w", err))
+	}
+	return mo.Ok(object.Packages)
+}
+
+var quotedString = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// parseCargoWorkspace reads "members" and "exclude" from the [workspace]
+// table of Cargo.toml. Excludes are returned negated.
+func parseCargoWorkspace(data []byte) mo.Result[[]string] {
+	var patterns []string
+	section := ""
+	key := ""
+	var value strings.Builder
+
+	flush := func() {
+		for _, m := range quotedString.FindAllStringSubmatch(value.String(), -1) {
+			item := m[1] + m[2]
+			if key == "exclude" {
+				item = "!" + item
+			}
+			patterns = append(patterns, item)
+		}
+		key = ""
+		value.Reset()
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+
+		if key != "" {
+			value.WriteString(line)
+			if strings.Contains(line, "]") {
+				flush()
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+
+		if section != "workspace" {
+			continue
+		}
+
+		name, rest, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || (name != "members" && name != "exclude") {
+			continue
+		}
+
+		key = name
+		value.WriteString(rest)
+		if strings.Contains(rest, "]") {
+			flush()
+		}
+	}
+
+	return mo.Ok(patterns)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+
+	"dev/internal/statefile"
+	"dev/internal/xdg"
+
+	"github.com/samber/mo"
+)
+
+// Entry is a project registered by hand.
+type Entry struct {
+	Path   string `json:"path"`
+	Pinned bool   `json:"pinned,omitempty"`
+}
+
+// Registry is the projects registered with `dev add` and `dev pin`, listed
+// whether or not a scan finds them, and the paths of projects hidden from
+// the list.
+type Registry struct {
+	Entries []Entry  `json:"entries,omitempty"`
+	Hidden  []string `json:"hidden,omitempty"`
+}
+
+var registryFile = statefile.File[Registry]{Dir: xdg.StateHome, Name: "projects.json", Version: 1, Indent: true}
+
+// Load returns the registered projects. A missing registry is empty, not an
+// error.
+func Load() mo.Result[Registry] {
+	r, err := registryFile.Load().Get()
+	if err != nil {
+		return mo.Err[Registry](fmt.Errorf("registry: %w", err))
+	}
+	return mo.Ok(r.OrEmpty())
+}
+
+// Save replaces the stored registry with r.
+func (r Registry) Save() error {
+	return registryFile.Save(r)
+}
+
+// Add registers path, keeping it pinned if it was. It reports whether path
+// was new.
+func (r *Registry) Add(path string) bool {
+	if slices.ContainsFunc(r.Entries, func(e Entry) bool { return e.Path == path }) {
+		return false
+	}
+	r.Entries = append(r.Entries, Entry{Path: path})
+	return true
+}
+
+// Pin registers path as pinned. It reports whether path was not pinned
+// before.
+func (r *Registry) Pin(path string) bool {
+	r.Add(path)
+	i := slices.IndexFunc(r.Entries, func(e Entry) bool { return e.Path == path })
+	if r.Entries[i].Pinned {
+		return false
+	}
+	r.Entries[i].Pinned = true
+	return true
+}
+
+// Remove unregisters path. It reports whether path was registered.
+func (r *Registry) Remove(path string) bool {
+	n := len(r.Entries)
+	r.Entries = slices.DeleteFunc(r.Entries, func(e Entry) bool { return e.Path == path })
+	return len(r.Entries) < n
+}
+
+// Hide hides the project at path from the list. It reports whether it was
+// shown before.
+func (r *Registry) Hide(path string) bool {
+	if slices.Contains(r.Hidden, path) {
+		return false
+	}
+	r.Hidden = append(r.Hidden, path)
+	return true
+}
+
+// Unhide shows the project at path in the list again. It reports whether
+// it was hidden.
+func (r *Registry) Unhide(path string) bool {
+	n := len(r.Hidden)
+	r.Hidden = slices.DeleteFunc(r.Hidden, func(hidden string) bool { return hidden == path })
+	return len(r.Hidden) < n
+}
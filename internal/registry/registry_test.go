@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func useStateDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	return dir
+}
+
+func TestLoad_MissingIsEmpty(t *testing.T) {
+	useStateDir(t)
+
+	r, err := Load().Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Entries) != 0 || len(r.Hidden) != 0 {
+		t.Errorf("expected an empty registry, got %+v", r)
+	}
+}
+
+func TestLoad_CorruptIsAnError(t *testing.T) {
+	dir := filepath.Join(useStateDir(t), "dev")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "projects.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load().Get(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestRegistry_SaveThenLoad(t *testing.T) {
+	useStateDir(t)
+
+	var r Registry
+	r.Add("/srv/app")
+	r.Pin("/srv/tools")
+	r.Hide("/src/old")
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := Load().MustGet()
+	if want := []Entry{{Path: "/srv/app"}, {Path: "/srv/tools", Pinned: true}}; !slices.Equal(loaded.Entries, want) {
+		t.Errorf("expected entries %v, got %v", want, loaded.Entries)
+	}
+	if want := []string{"/src/old"}; !slices.Equal(loaded.Hidden, want) {
+		t.Errorf("expected %v hidden, got %v", want, loaded.Hidden)
+	}
+}
+
+func TestRegistry_HideAndUnhide(t *testing.T) {
+	useStateDir(t)
+
+	var r Registry
+	if !r.Hide("/src/old") || r.Hide("/src/old") {
+		t.Error("expected only the first Hide to hide")
+	}
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	r = Load().MustGet()
+	if !r.Unhide("/src/old") || r.Unhide("/src/old") {
+		t.Error("expected only the first Unhide to show it again")
+	}
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if hidden := Load().MustGet().Hidden; len(hidden) != 0 {
+		t.Errorf("expected nothing hidden, got %v", hidden)
+	}
+}
+
+func TestRegistry_PinKeepsOneEntry(t *testing.T) {
+	var r Registry
+	if !r.Add("/srv/app") || r.Add("/srv/app") {
+		t.Error("expected only the first Add to add")
+	}
+	if !r.Pin("/srv/app") || r.Pin("/srv/app") {
+		t.Error("expected only the first Pin to pin")
+	}
+	if r.Add("/srv/app") {
+		t.Error("expected Add to leave a pinned entry alone")
+	}
+	if want := []Entry{{Path: "/srv/app", Pinned: true}}; !slices.Equal(r.Entries, want) {
+		t.Errorf("expected %v, got %v", want, r.Entries)
+	}
+	if !r.Remove("/srv/app") || r.Remove("/srv/app") {
+		t.Error("expected only the first Remove to remove")
+	}
+}
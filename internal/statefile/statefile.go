@@ -0,0 +1,103 @@
+// Package statefile keeps the small JSON files dev remembers things in
+// between runs, such as the cache, the history and the registry.
+package statefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"dev/internal/filesystem"
+
+	"github.com/samber/mo"
+)
+
+// File is a JSON file holding a T, which must encode as a JSON object. The
+// file also records Version, which is bumped whenever the layout of T
+// changes, so that files written before are started over instead of being
+// misread.
+type File[T any] struct {
+	// Dir returns the directory the file is in, such as xdg.StateHome.
+	Dir     func() mo.Result[string]
+	Name    string
+	Version int
+	// Indent makes the file easier to read by hand, for small files.
+	Indent bool
+}
+
+type header struct {
+	Version int `json:"version"`
+}
+
+// Path returns where the file is.
+func (f File[T]) Path() mo.Result[string] {
+	dir, err := f.Dir().Get()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(filepath.Join(dir, f.Name))
+}
+
+// Load returns what was saved last, or nothing when the file is missing or
+// was written with another version. A file that cannot be read or decoded
+// is an error.
+func (f File[T]) Load() mo.Result[mo.Option[T]] {
+	path, err := f.Path().Get()
+	if err != nil {
+		return mo.Err[mo.Option[T]](err)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mo.Ok(mo.None[T]())
+	}
+	if err != nil {
+		return mo.Err[mo.Option[T]](err)
+	}
+
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return mo.Err[mo.Option[T]](fmt.Errorf("%s: %w", path, err))
+	}
+	if h.Version != f.Version {
+		return mo.Ok(mo.None[T]())
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return mo.Err[mo.Option[T]](fmt.Errorf("%s: %w", path, err))
+	}
+	return mo.Ok(mo.Some(v))
+}
+
+// Save replaces the file with v. It is replaced atomically, so a
+// concurrent Load never sees half of it.
+func (f File[T]) Save(v T) error {
+	path, err := f.Path().Get()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	fields["version"], _ = json.Marshal(f.Version)
+
+	if f.Indent {
+		data, err = json.MarshalIndent(fields, "", "  ")
+	} else {
+		data, err = json.Marshal(fields)
+	}
+	if err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(path, data)
+}
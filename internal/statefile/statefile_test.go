@@ -0,0 +1,101 @@
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/samber/mo"
+)
+
+type notes struct {
+	Lines []string `json:"lines"`
+}
+
+func testFile(t *testing.T, version int) File[notes] {
+	dir := t.TempDir()
+	return File[notes]{
+		Dir:     func() mo.Result[string] { return mo.Ok(dir) },
+		Name:    "notes.json",
+		Version: version,
+	}
+}
+
+func TestFile_SaveThenLoad(t *testing.T) {
+	f := testFile(t, 1)
+	if err := f.Save(notes{Lines: []string{"a", "b"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := f.Load().Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, ok := loaded.Get()
+	if !ok || !slices.Equal(got.Lines, []string{"a", "b"}) {
+		t.Errorf("expected the saved lines, got %v (found %t)", got.Lines, ok)
+	}
+}
+
+func TestFile_KeepsLayoutWithVersion(t *testing.T) {
+	f := testFile(t, 3)
+	f.Indent = true
+	if err := f.Save(notes{Lines: []string{"a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(f.Path().MustGet())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n  \"lines\": [\n    \"a\"\n  ],\n  \"version\": 3\n}"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestFile_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		found   bool
+		wantErr bool
+	}{
+		{name: "missing"},
+		{name: "written before", content: `{"version": 1, "lines": ["a"]}`, found: true},
+		{name: "other version", content: `{"version": 2, "lines": ["a"]}`},
+		{name: "no version", content: `{"lines": ["a"]}`},
+		{name: "corrupt", content: `{"version": 1, "lines": [`, wantErr: true},
+		{name: "wrong shape", content: `{"version": 1, "lines": "a"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		f := testFile(t, 1)
+		if tt.content != "" {
+			if err := os.WriteFile(f.Path().MustGet(), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		loaded, err := f.Load().Get()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if loaded.IsPresent() != tt.found {
+			t.Errorf("%s: expected found %t, got %t", tt.name, tt.found, loaded.IsPresent())
+		}
+	}
+}
+
+func TestFile_SaveCreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state", "dev")
+	f := File[notes]{Dir: func() mo.Result[string] { return mo.Ok(dir) }, Name: "notes.json", Version: 1}
+	if err := f.Save(notes{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.json")); err != nil {
+		t.Errorf("expected the file to be written: %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 
 	"github.com/samber/mo"
 )
@@ -13,52 +15,120 @@ type Terminal interface {
 	RenameTab(name string) error
 }
 
-func Detect() Terminal {
+// Options change how the editor is opened. The zero value opens $EDITOR
+// in place of dev.
+type Options struct {
+	// Editor is the command run when neither $EDITOR nor $VISUAL is set.
+	Editor string
+	// ProfileEditor is the command of the chosen profile. Choosing a
+	// profile is explicit, so it is run even when $EDITOR is set.
+	ProfileEditor string
+	// Launch is how the editor is opened inside a multiplexer, by its name.
+	// See Launches.
+	Launch map[string]string
+	// KeepTabName lists the multiplexers whose tab is left as it is.
+	KeepTabName map[string]bool
+}
+
+// Launches are the ways each multiplexer can open the editor. The first
+// is the default: replacing the pane dev runs in.
+var Launches = map[string][]string{
+	"tmux":   {"replace", "window", "split"},
+	"zellij": {"replace", "pane", "floating"},
+}
+
+// Detect returns the terminal dev runs in. It fails for options naming a
+// multiplexer or launch it does not know.
+func Detect(opts Options) mo.Result[Terminal] {
+	for name := range opts.KeepTabName {
+		if _, ok := Launches[name]; !ok {
+			return mo.Err[Terminal](fmt.Errorf("unknown terminal %q", name))
+		}
+	}
+	for name, launch := range opts.Launch {
+		launches, ok := Launches[name]
+		if !ok {
+			return mo.Err[Terminal](fmt.Errorf("unknown terminal %q", name))
+		}
+		if !slices.Contains(launches, launch) {
+			return mo.Err[Terminal](fmt.Errorf("terminal %s cannot launch %q, only %s", name, launch, strings.Join(launches, ", ")))
+		}
+	}
+
 	if os.Getenv("ZELLIJ") != "" {
-		return &Zellij{}
+		return mo.Ok[Terminal](&Zellij{opts: opts})
 	}
 	if os.Getenv("TMUX") != "" {
-		return &Tmux{}
+		return mo.Ok[Terminal](&Tmux{opts: opts})
 	}
-	return &Default{}
+	return mo.Ok[Terminal](&Default{opts: opts})
 }
 
-type Zellij struct{}
+type Zellij struct {
+	opts Options
+}
 
 func (z *Zellij) OpenEditor(path string) mo.Result[string] {
-	editor, err := getEditorFromEnv().Get()
+	editor, err := editorCommand(z.opts).Get()
 	if err != nil {
 		return mo.Err[string](err)
 	}
-	return run("zellij", "", "run", "--cwd", path, "-c", "-i", "--", editor)
+
+	args := []string{"run", "--cwd", path, "-c"}
+	switch z.opts.Launch["zellij"] {
+	case "pane":
+	case "floating":
+		args = append(args, "-f")
+	default:
+		args = append(args, "-i")
+	}
+	return run("zellij", "", append(append(args, "--"), editor...)...)
 }
 
 func (z *Zellij) RenameTab(name string) error {
+	if z.opts.KeepTabName["zellij"] {
+		return nil
+	}
 	return exec.Command("zellij", "action", "rename-tab", name).Run()
 }
 
-type Tmux struct{}
+type Tmux struct {
+	opts Options
+}
 
 func (t *Tmux) OpenEditor(path string) mo.Result[string] {
-	editor, err := getEditorFromEnv().Get()
+	editor, err := editorCommand(t.opts).Get()
 	if err != nil {
 		return mo.Err[string](err)
 	}
-	return run("tmux", "", "respawn-pane", "-k", "-c", path, editor)
+
+	command := strings.Join(editor, " ")
+	switch t.opts.Launch["tmux"] {
+	case "window":
+		return run("tmux", "", "new-window", "-c", path, command)
+	case "split":
+		return run("tmux", "", "split-window", "-c", path, command)
+	}
+	return run("tmux", "", "respawn-pane", "-k", "-c", path, command)
 }
 
 func (t *Tmux) RenameTab(name string) error {
+	if t.opts.KeepTabName["tmux"] {
+		return nil
+	}
 	return exec.Command("tmux", "rename-window", name).Run()
 }
 
-type Default struct{}
+type Default struct {
+	opts Options
+}
 
 func (d *Default) OpenEditor(path string) mo.Result[string] {
-	editor, err := getEditorFromEnv().Get()
+	editor, err := editorCommand(d.opts).Get()
 	if err != nil {
 		return mo.Err[string](err)
 	}
-	return run(editor, path, ".")
+	return run(editor[0], path, append(editor[1:], ".")...)
 }
 
 func (d *Default) RenameTab(name string) error {
@@ -84,12 +154,28 @@ func run(name string, dir string, args ...string) mo.Result[string] {
 	return mo.Ok(path)
 }
 
-func getEditorFromEnv() mo.Result[string] {
+// Editor returns the editor command opts pick, from the profile, $EDITOR,
+// $VISUAL or else opts.Editor, and which of the first three it came from.
+func Editor(opts Options) (string, string) {
+	if opts.ProfileEditor != "" {
+		return opts.ProfileEditor, "profile"
+	}
 	if editor := os.Getenv("EDITOR"); editor != "" {
-		return mo.Ok(editor)
+		return editor, "$EDITOR"
 	}
 	if editor := os.Getenv("VISUAL"); editor != "" {
-		return mo.Ok(editor)
+		return editor, "$VISUAL"
+	}
+	return opts.Editor, ""
+}
+
+// editorCommand splits the editor command into the program and its
+// arguments, so that an editor such as "code --wait" works.
+func editorCommand(opts Options) mo.Result[[]string] {
+	editor, _ := Editor(opts)
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return mo.Err[[]string](fmt.Errorf("$VISUAL or $EDITOR is not set, and neither is editor in config.toml"))
 	}
-	return mo.Err[string](fmt.Errorf("$VISUAL or $EDITOR is not set"))
+	return mo.Ok(fields)
 }
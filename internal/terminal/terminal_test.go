@@ -0,0 +1,216 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets everything Detect and Editor read from the environment.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"TMUX", "ZELLIJ", "EDITOR", "VISUAL"} {
+		t.Setenv(name, "")
+	}
+}
+
+// fakeMultiplexers puts tmux and zellij on $PATH as scripts that note the
+// arguments they are run with, and returns a function reading those notes.
+func fakeMultiplexers(t *testing.T) func() []string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	for _, name := range []string{"tmux", "zellij"} {
+		script := "#!/bin/sh\necho \"" + name + " $*\" >> \"" + log + "\"\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	return func() []string {
+		data, err := os.ReadFile(log)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		opts    Options
+		want    Terminal
+		wantErr string
+	}{
+		{name: "outside a multiplexer", want: &Default{}},
+		{name: "tmux", env: "TMUX", want: &Tmux{}},
+		{name: "zellij", env: "ZELLIJ", want: &Zellij{}},
+		{name: "tmux window", env: "TMUX", opts: Options{Launch: map[string]string{"tmux": "window"}}, want: &Tmux{}},
+		{name: "tmux split", opts: Options{Launch: map[string]string{"tmux": "split"}}, want: &Default{}},
+		{name: "zellij floating", opts: Options{Launch: map[string]string{"zellij": "floating"}}, want: &Default{}},
+		{name: "launch of another multiplexer", opts: Options{Launch: map[string]string{"tmux": "floating"}}, wantErr: `terminal tmux cannot launch "floating", only replace, window, split`},
+		{name: "misspelt launch", opts: Options{Launch: map[string]string{"zellij": "panes"}}, wantErr: `terminal zellij cannot launch "panes", only replace, pane, floating`},
+		{name: "unknown multiplexer launch", opts: Options{Launch: map[string]string{"screen": "window"}}, wantErr: `unknown terminal "screen"`},
+		{name: "unknown multiplexer tab name", opts: Options{KeepTabName: map[string]bool{"screen": true}}, wantErr: `unknown terminal "screen"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.env != "" {
+				t.Setenv(tt.env, "1")
+			}
+
+			got, err := Detect(tt.opts).Get()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("expected %T, got %T", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLaunches(t *testing.T) {
+	clearEnv(t)
+	for name, launches := range Launches {
+		if launches[0] != "replace" {
+			t.Errorf("expected %s to replace dev's pane by default, got %q", name, launches[0])
+		}
+		for _, launch := range launches {
+			if err := Detect(Options{Launch: map[string]string{name: launch}}).Error(); err != nil {
+				t.Errorf("expected %s to launch %q, got %v", name, launch, err)
+			}
+		}
+	}
+}
+
+func TestOpenEditor_Launches(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		launch map[string]string
+		want   string
+	}{
+		{name: "tmux default", env: "TMUX", want: "tmux respawn-pane -k -c /src/api nvim -p"},
+		{name: "tmux replace", env: "TMUX", launch: map[string]string{"tmux": "replace"}, want: "tmux respawn-pane -k -c /src/api nvim -p"},
+		{name: "tmux window", env: "TMUX", launch: map[string]string{"tmux": "window"}, want: "tmux new-window -c /src/api nvim -p"},
+		{name: "tmux split", env: "TMUX", launch: map[string]string{"tmux": "split"}, want: "tmux split-window -c /src/api nvim -p"},
+		{name: "tmux ignores zellij launch", env: "TMUX", launch: map[string]string{"zellij": "pane"}, want: "tmux respawn-pane -k -c /src/api nvim -p"},
+		{name: "zellij default", env: "ZELLIJ", want: "zellij run --cwd /src/api -c -i -- nvim -p"},
+		{name: "zellij replace", env: "ZELLIJ", launch: map[string]string{"zellij": "replace"}, want: "zellij run --cwd /src/api -c -i -- nvim -p"},
+		{name: "zellij pane", env: "ZELLIJ", launch: map[string]string{"zellij": "pane"}, want: "zellij run --cwd /src/api -c -- nvim -p"},
+		{name: "zellij floating", env: "ZELLIJ", launch: map[string]string{"zellij": "floating"}, want: "zellij run --cwd /src/api -c -f -- nvim -p"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv(tt.env, "1")
+			ran := fakeMultiplexers(t)
+
+			term := Detect(Options{Editor: "nvim -p", Launch: tt.launch}).MustGet()
+			if err := term.OpenEditor("/src/api").Error(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ran(); !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("expected %q to be run, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOpenEditor_WithoutEditor(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TMUX", "1")
+	ran := fakeMultiplexers(t)
+
+	err := Detect(Options{}).MustGet().OpenEditor("/src/api").Error()
+	if err == nil || !strings.Contains(err.Error(), "is not set") {
+		t.Errorf("expected an error saying no editor is set, got %v", err)
+	}
+	if got := ran(); got != nil {
+		t.Errorf("expected nothing to be run, got %q", got)
+	}
+}
+
+func TestRenameTab_KeepTabName(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		keep map[string]bool
+		want []string
+	}{
+		{name: "tmux", env: "TMUX", want: []string{"tmux rename-window api"}},
+		{name: "tmux kept", env: "TMUX", keep: map[string]bool{"tmux": true}},
+		{name: "tmux kept only in zellij", env: "TMUX", keep: map[string]bool{"zellij": true}, want: []string{"tmux rename-window api"}},
+		{name: "zellij", env: "ZELLIJ", want: []string{"zellij action rename-tab api"}},
+		{name: "zellij kept", env: "ZELLIJ", keep: map[string]bool{"zellij": true}},
+		{name: "zellij renamed explicitly", env: "ZELLIJ", keep: map[string]bool{"zellij": false}, want: []string{"zellij action rename-tab api"}},
+		{name: "outside a multiplexer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.env != "" {
+				t.Setenv(tt.env, "1")
+			}
+			ran := fakeMultiplexers(t)
+
+			if err := Detect(Options{KeepTabName: tt.keep}).MustGet().RenameTab("api"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ran(); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %q to be run, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		editor     string
+		visual     string
+		file       string
+		want       string
+		wantSource string
+	}{
+		{name: "profile over everything", profile: "code --wait", editor: "vim", visual: "emacs", file: "nano", want: "code --wait", wantSource: "profile"},
+		{name: "$EDITOR over $VISUAL", editor: "vim", visual: "emacs", file: "nano", want: "vim", wantSource: "$EDITOR"},
+		{name: "$VISUAL over config.toml", visual: "emacs", file: "nano", want: "emacs", wantSource: "$VISUAL"},
+		{name: "config.toml", file: "nano", want: "nano"},
+		{name: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("EDITOR", tt.editor)
+			t.Setenv("VISUAL", tt.visual)
+
+			got, source := Editor(Options{ProfileEditor: tt.profile, Editor: tt.file})
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("expected %q from %q, got %q from %q", tt.want, tt.wantSource, got, source)
+			}
+		})
+	}
+}
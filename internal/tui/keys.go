@@ -1,6 +1,11 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/samber/lo"
+)
 
 type KeyMap struct {
 	NextItem   key.Binding
@@ -9,6 +14,10 @@ type KeyMap struct {
 	Cancel     key.Binding
 	Backspace  key.Binding
 	ClearQuery key.Binding
+	Sort       key.Binding
+	Hide       key.Binding
+	ShowHidden key.Binding
+	Profile    key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -37,5 +46,60 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+c"),
 			key.WithHelp("ctrl+c", "clear query"),
 		),
+		Sort: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "sort"),
+		),
+		Hide: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "hide"),
+		),
+		ShowHidden: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "hidden"),
+		),
+		Profile: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "profile"),
+		),
+	}
+}
+
+// named returns the bindings by the names config.toml uses for them.
+func (k *KeyMap) named() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"next":        &k.NextItem,
+		"prev":        &k.PrevItem,
+		"select":      &k.Select,
+		"cancel":      &k.Cancel,
+		"backspace":   &k.Backspace,
+		"clear_query": &k.ClearQuery,
+		"sort":        &k.Sort,
+		"hide":        &k.Hide,
+		"show_hidden": &k.ShowHidden,
+		"profile":     &k.Profile,
 	}
 }
+
+// Rebind replaces the keys of the binding called name. The first key is
+// the one shown in hints.
+func (k *KeyMap) Rebind(name string, keys []string) error {
+	binding, ok := k.named()[name]
+	if !ok {
+		return fmt.Errorf("unknown key binding %q", name)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("key binding %q has no keys", name)
+	}
+
+	binding.SetKeys(keys...)
+	binding.SetHelp(keys[0], binding.Help().Desc)
+	return nil
+}
+
+// Named returns the keys of every binding by its name.
+func (k KeyMap) Named() map[string][]string {
+	return lo.MapValues(k.named(), func(b *key.Binding, _ string) []string {
+		return b.Keys()
+	})
+}
@@ -2,18 +2,68 @@ package tui
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"dev/internal/projects"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
 )
 
 type Icons struct {
-	Dir  string
-	Term string
+	Dir       string
+	Term      string
+	Worktree  string
+	Workspace string
+	Warning   string
+	Bare      string
+	Dirty     string
+	Ahead     string
+	Behind    string
+	Remote    string
+	Pin       string
+	Hidden    string
+}
+
+// named returns the icons by the names config.toml uses for them.
+func (i *Icons) named() map[string]*string {
+	return map[string]*string{
+		"dir":       &i.Dir,
+		"term":      &i.Term,
+		"worktree":  &i.Worktree,
+		"workspace": &i.Workspace,
+		"warning":   &i.Warning,
+		"bare":      &i.Bare,
+		"dirty":     &i.Dirty,
+		"ahead":     &i.Ahead,
+		"behind":    &i.Behind,
+		"remote":    &i.Remote,
+		"pin":       &i.Pin,
+		"hidden":    &i.Hidden,
+	}
+}
+
+// Set replaces the icon called name.
+func (i *Icons) Set(name, icon string) error {
+	field, ok := i.named()[name]
+	if !ok {
+		return fmt.Errorf("unknown icon %q", name)
+	}
+	*field = icon
+	return nil
+}
+
+// Named returns every icon by its name.
+func (i Icons) Named() map[string]string {
+	return lo.MapValues(i.named(), func(icon *string, _ string) string {
+		return *icon
+	})
 }
 
 // Layout constants
@@ -32,18 +82,68 @@ const (
 )
 
 type Model struct {
-	keys     KeyMap
-	projects []projects.Project
-	filtered []projects.Project
-	query    string
-	cursor   int
-	Selected string
-	width    int
-	height   int
-	quitting bool
-	icons    Icons
+	keys       KeyMap
+	projects   []projects.Project
+	filtered   []projects.Project
+	query      string
+	sort       projects.SortMode
+	frecency   map[string]float64
+	registered []projects.Project
+	hidden     map[string]bool
+	showHidden bool
+	saveHidden func(path string, hidden bool) error
+	profiles   []string
+	profile    string
+	load       func(profile string) mo.Result[Listing]
+	switching  mo.Option[string]
+	profileErr error
+	cursor     int
+	Selected   string
+	width      int
+	height     int
+	quitting   bool
+	icons      Icons
+	scan       *projects.Scan
+	scanning   bool
+	streamed   int
+	previous   []projects.Project
+	timedOut   []string
+	errCount   int
+	spinner    spinner.Model
+	status     *projects.StatusCollector
+	statuses   map[string]projects.Status
+	reread     *projects.Collector[projects.Project]
+	names      map[string]string
+	err        error
+}
+
+// scanMsg carries the projects a running scan found since the last one.
+type scanMsg struct {
+	scan  *projects.Scan
+	found []projects.Project
+	done  bool
+}
+
+// profileMsg carries what the profile the user switched to lists.
+type profileMsg struct {
+	profile string
+	listing Listing
+	err     error
+}
+
+// Listing is what a profile lists: the projects known right away, and the
+// scan finding the rest while the list is shown, if one runs.
+type Listing struct {
+	Projects []projects.Project
+	Scan     *projects.Scan
 }
 
+// statusMsg carries the statuses computed since the last one.
+type statusMsg []projects.StatusUpdate
+
+// rereadMsg carries projects read in again since the last one.
+type rereadMsg []projects.Update[projects.Project]
+
 type layout struct {
 	isSmall       bool
 	contentWidth  int
@@ -52,16 +152,143 @@ type layout struct {
 }
 
 func NewModel(p []projects.Project, keys KeyMap, icons Icons) Model {
+	p = arrange(p)
 	return Model{
 		keys:     keys,
 		projects: p,
 		filtered: p,
+		sort:     projects.SortScore,
+		names:    projects.DisplayNames(p),
 		icons:    icons,
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(pathStyle)),
+	}
+}
+
+// WithScan streams the projects found by scan into the list while it runs.
+// Projects the model was created with stay listed until the scan completes,
+// at which point the list becomes what the scan found, keeping earlier
+// projects only below search paths the scan did not finish.
+func (m Model) WithScan(scan *projects.Scan) Model {
+	m.scan = scan
+	m.scanning = true
+	m.previous = m.projects
+	return m
+}
+
+// WithListing lists what l has, replacing the current list. The projects
+// known right away are read in again with WithReread.
+func (m Model) WithListing(l Listing) Model {
+	if m.reread != nil {
+		m.reread.Request(l.Projects)
+	}
+	m.setProjects(l.Projects)
+	m.scan, m.scanning, m.streamed = nil, false, 0
+	m.timedOut, m.errCount = nil, 0
+	if l.Scan != nil {
+		m = m.WithScan(l.Scan)
+	}
+	return m
+}
+
+// WithProfiles lets the user cycle through profiles, current being the
+// one listed now. load lists a profile; it may block, and runs outside
+// the UI.
+func (m Model) WithProfiles(profiles []string, current string, load func(profile string) mo.Result[Listing]) Model {
+	m.profiles = profiles
+	m.profile = current
+	m.load = load
+	return m
+}
+
+// WithSort lists projects in the given order until the user picks another.
+func (m Model) WithSort(mode projects.SortMode) Model {
+	if mode != "" {
+		m.sort = mode
 	}
+	m.refilter()
+	return m
+}
+
+// WithRegistered lists the registered projects along with the ones found,
+// and marks those pinned there.
+func (m Model) WithRegistered(registered []projects.Project) Model {
+	m.registered = registered
+	m.setProjects(m.projects)
+	return m
+}
+
+// WithHidden leaves the projects at hidden out of the list until they are
+// revealed, and lets the user hide more or show them again, calling save
+// to remember each change.
+func (m Model) WithHidden(hidden []string, save func(path string, hidden bool) error) Model {
+	m.hidden = lo.SliceToMap(hidden, func(path string) (string, bool) { return path, true })
+	m.saveHidden = save
+	m.refilter()
+	return m
+}
+
+// WithFrecency ranks projects by scores, keyed by path, when the list is
+// ordered by score: alone without a query, and between equal matches with
+// one.
+func (m Model) WithFrecency(scores map[string]float64) Model {
+	m.frecency = scores
+	m.refilter()
+	return m
+}
+
+// WithStatus shows the working tree status of listed projects as status
+// computes it, asking for every project that gets listed.
+func (m Model) WithStatus(status *projects.StatusCollector) Model {
+	m.status = status
+	m.statuses = make(map[string]projects.Status)
+	status.Request(m.projects)
+	return m
+}
+
+// WithReread has reread read in again what may have changed about the
+// projects listed before the picker opened, such as cached ones, and
+// updates them as it does. Found projects are read in by the scan itself.
+func (m Model) WithReread(reread *projects.Collector[projects.Project]) Model {
+	m.reread = reread
+	reread.Request(m.projects)
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	var cmds []tea.Cmd
+	if m.scanning {
+		cmds = append(cmds, m.spinner.Tick, waitForScan(m.scan, 0))
+	}
+	if m.status != nil {
+		cmds = append(cmds, waitForUpdates[projects.Status, statusMsg](m.status))
+	}
+	if m.reread != nil {
+		cmds = append(cmds, waitForUpdates[projects.Project, rereadMsg](m.reread))
+	}
+	return tea.Batch(cmds...)
+}
+
+func waitForScan(scan *projects.Scan, from int) tea.Cmd {
+	return func() tea.Msg {
+		found, done := scan.Next(from)
+		return scanMsg{scan: scan, found: found, done: done}
+	}
+}
+
+// waitForUpdates blocks for the next update of c and picks up any others
+// already waiting, so a burst of them is drawn once.
+func waitForUpdates[T any, M ~[]projects.Update[T]](c *projects.Collector[T]) tea.Cmd {
+	return func() tea.Msg {
+		msg := M{<-c.Updates()}
+		for {
+			select {
+			case u := <-c.Updates():
+				msg = append(msg, u)
+			default:
+				return msg
+			}
+		}
+	}
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -71,6 +298,93 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case spinner.TickMsg:
+		if !m.scanning {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case statusMsg:
+		for _, u := range msg {
+			m.statuses[u.Path] = u.Value
+		}
+		return m, waitForUpdates[projects.Status, statusMsg](m.status)
+
+	case rereadMsg:
+		reread := lo.SliceToMap(msg, func(u projects.Update[projects.Project]) (string, projects.Project) {
+			return u.Path, u.Value
+		})
+		replace := func(list []projects.Project) []projects.Project {
+			return lo.Map(list, func(p projects.Project, _ int) projects.Project {
+				if r, ok := reread[p.Path]; ok {
+					// The list decides how a project is shown, such as
+					// pinned, so only what was read in is taken over.
+					p.Head, p.Remote, p.LastActivity = r.Head, r.Remote, r.LastActivity
+				}
+				return p
+			})
+		}
+		m.projects = replace(m.projects)
+		m.registered = replace(m.registered)
+		m.previous = replace(m.previous)
+		m.refilter()
+		m.names = projects.DisplayNames(m.projects)
+		return m, waitForUpdates[projects.Project, rereadMsg](m.reread)
+
+	case scanMsg:
+		if msg.scan != m.scan {
+			// Left over from the profile listed before.
+			return m, nil
+		}
+		m.streamed += len(msg.found)
+		if !msg.done {
+			m.setProjects(mergeProjects(m.projects, msg.found))
+			return m, waitForScan(m.scan, m.streamed)
+		}
+
+		m.scanning = false
+		m.timedOut = m.scan.TimedOut()
+		_, errs := m.scan.Wait()
+		m.errCount = len(errs)
+		m.setProjects(m.scan.Merge(m.previous))
+		if len(m.projects) == 0 && len(m.profiles) > 1 {
+			// Another profile may list some.
+			m.profileErr = fmt.Errorf("no projects found")
+			return m, nil
+		}
+		if len(m.projects) == 0 {
+			m.err = fmt.Errorf("no projects found")
+			if len(errs) > 0 {
+				m.err = errs[0]
+			} else if len(m.timedOut) > 0 {
+				m.err = fmt.Errorf("no projects found before the scan timed out")
+			}
+			m.quitting = true
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case profileMsg:
+		if m.quitting || m.switching != mo.Some(msg.profile) {
+			// Another profile was picked while this one loaded, or the
+			// picker is closing with a project from the listed one.
+			return m, nil
+		}
+		m.switching = mo.None[string]()
+		if msg.err != nil {
+			m.profileErr = msg.err
+			return m, nil
+		}
+		m.profile = msg.profile
+		m = m.WithListing(msg.listing)
+		m.cursor = 0
+		if m.scanning {
+			return m, tea.Batch(m.spinner.Tick, waitForScan(m.scan, 0))
+		}
+		return m, nil
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Cancel):
@@ -100,15 +414,62 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(m.query) > 0 {
 				runes := []rune(m.query)
 				m.query = string(runes[:len(runes)-1])
-				m.filtered = projects.Filter(m.projects, m.query)
+				m.refilter()
 				m.cursor = 0
 			}
 			return m, nil
 
+		case key.Matches(msg, m.keys.Sort):
+			m.sort = m.sort.Next()
+			m.refilter()
+			m.cursor = 0
+			return m, nil
+
+		case key.Matches(msg, m.keys.Hide):
+			if m.saveHidden == nil || m.cursor >= len(m.filtered) {
+				return m, nil
+			}
+			path := m.filtered[m.cursor].Path
+			hide := !m.hidden[path]
+			if hide {
+				m.hidden[path] = true
+			} else {
+				delete(m.hidden, path)
+			}
+			m.refilter()
+			m.cursor = min(m.cursor, max(len(m.filtered)-1, 0))
+			save := m.saveHidden
+			return m, func() tea.Msg {
+				// Like the history, this is not worth interrupting the
+				// picker for when it cannot be saved.
+				_ = save(path, hide)
+				return nil
+			}
+
+		case key.Matches(msg, m.keys.ShowHidden):
+			m.showHidden = !m.showHidden
+			m.refilter()
+			m.cursor = 0
+			return m, nil
+
+		case key.Matches(msg, m.keys.Profile):
+			if len(m.profiles) < 2 {
+				return m, nil
+			}
+			// The projects listed stay until the next profile's are known.
+			i := slices.Index(m.profiles, m.switching.OrElse(m.profile))
+			profile, load := m.profiles[(i+1)%len(m.profiles)], m.load
+			m.switching = mo.Some(profile)
+			m.profileErr = nil
+			return m, func() tea.Msg {
+				l, err := load(profile).Get()
+				return profileMsg{profile: profile, listing: l, err: err}
+			}
+
 		case key.Matches(msg, m.keys.ClearQuery):
 			if len(m.query) > 0 {
 				m.query = ""
-				m.filtered = m.projects
+				m.refilter()
 				m.cursor = 0
 			}
 			return m, nil
@@ -116,7 +477,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		default:
 			if msg.Type == tea.KeyRunes {
 				m.query += string(msg.Runes)
-				m.filtered = projects.Filter(m.projects, m.query)
+				m.refilter()
 				m.cursor = 0
 			}
 			return m, nil
@@ -126,12 +487,89 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// setProjects swaps the project list while keeping the query and, when it
+// is still listed, the project under the cursor.
+func (m *Model) setProjects(p []projects.Project) {
+	var current string
+	if m.cursor < len(m.filtered) {
+		current = m.filtered[m.cursor].Path
+	}
+
+	m.projects = arrange(projects.MergeRegistered(p, m.registered))
+	m.refilter()
+	m.names = projects.DisplayNames(m.projects)
+	if m.status != nil {
+		m.status.Request(m.projects)
+	}
+	m.cursor = max(slices.IndexFunc(m.filtered, func(p projects.Project) bool {
+		return p.Path == current
+	}), 0)
+}
+
+func (m *Model) refilter() {
+	m.filtered = projects.Sort(projects.Filter(m.visible(), m.query, m.frecency), m.sort)
+}
+
+// visible returns the projects to list: all but the hidden ones, or all of
+// them with the hidden ones marked while they are revealed.
+func (m Model) visible() []projects.Project {
+	if len(m.hidden) == 0 {
+		return m.projects
+	}
+
+	visible := make([]projects.Project, 0, len(m.projects))
+	for _, p := range m.projects {
+		if m.hidden[p.Path] {
+			if !m.showHidden {
+				continue
+			}
+			p.Hidden = true
+		}
+		visible = append(visible, p)
+	}
+	return visible
+}
+
+// arrange puts projects in a fixed order regardless of the order the walk
+// found them in: by path, with the worktrees of bare repositories below
+// them.
+func arrange(p []projects.Project) []projects.Project {
+	return projects.GroupWorktrees(projects.Sort(p, projects.SortPath))
+}
+
+// displayed returns list with each name replaced by the one that tells it
+// apart from other projects of the same name.
+func (m Model) displayed(list []projects.Project) []projects.Project {
+	return lo.Map(list, func(p projects.Project, _ int) projects.Project {
+		if name, ok := m.names[p.Path]; ok {
+			p.Name = name
+		}
+		return p
+	})
+}
+
+// mergeProjects appends the projects in found that are not listed yet.
+func mergeProjects(listed, found []projects.Project) []projects.Project {
+	paths := make(map[string]struct{}, len(listed))
+	for _, p := range listed {
+		paths[p.Path] = struct{}{}
+	}
+
+	merged := slices.Clip(listed)
+	for _, p := range found {
+		if _, exists := paths[p.Path]; !exists {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
 func (m Model) View() string {
 	if m.width == 0 || m.height == 0 {
 		return ""
 	}
 
-	l := calculateLayout(m.width, m.height, maxLineWidth(m.projects))
+	l := calculateLayout(m.width, m.height, maxLineWidth(m.displayed(m.projects), m.statuses, m.icons))
 
 	if l.isSmall {
 		return viewSmall(m, l)
@@ -141,10 +579,10 @@ func (m Model) View() string {
 }
 
 func viewSmall(m Model, l layout) string {
-	content := renderHeader(l.innerWidth, m.keys, len(m.filtered), len(m.projects)) +
+	content := renderHeader(l.innerWidth, m.keys, len(m.filtered), len(m.projects), m.profileStatus()+m.scanStatus()+m.hiddenStatus()) +
 		renderInput(m.query) +
 		renderList(m, l, m.filtered, m.cursor, 0) +
-		renderFooter(l.innerWidth, m.keys)
+		renderFooter(l.innerWidth, m.keys, m.sort, len(m.profiles) > 1)
 
 	return "\n " + strings.ReplaceAll(content, "\n", "\n ")
 }
@@ -153,10 +591,10 @@ func viewBoxed(m Model, l layout) string {
 	fixedHeight := max(len(m.projects), minFixedListHeight)
 	fixedHeight = min(fixedHeight, maxBoxedListHeight)
 	fixedHeight = min(fixedHeight, l.maxListHeight)
-	content := renderHeader(l.innerWidth, m.keys, len(m.filtered), len(m.projects)) +
+	content := renderHeader(l.innerWidth, m.keys, len(m.filtered), len(m.projects), m.profileStatus()+m.scanStatus()+m.hiddenStatus()) +
 		renderInput(m.query) +
 		renderList(m, l, m.filtered, m.cursor, fixedHeight) +
-		renderFooter(l.innerWidth, m.keys)
+		renderFooter(l.innerWidth, m.keys, m.sort, len(m.profiles) > 1)
 
 	box := borderStyle.Width(l.contentWidth).Render(content)
 
@@ -184,9 +622,57 @@ func calculateLayout(width, height, maxLineWidth int) layout {
 	}
 }
 
-func renderHeader(innerWidth int, keys KeyMap, filteredCount, totalCount int) string {
+// scanStatus shows a spinner while the scan runs, and afterwards how many
+// errors it ran into and the search paths it did not finish in time.
+func (m Model) scanStatus() string {
+	if m.scanning {
+		return " " + m.spinner.View()
+	}
+
+	status := ""
+	if m.errCount > 0 {
+		status += warningStyle.Render(fmt.Sprintf(" %s %d %s", m.icons.Warning, m.errCount, lo.Ternary(m.errCount == 1, "error", "errors")))
+	}
+	if len(m.timedOut) > 0 {
+		names := lo.Map(m.timedOut, func(path string, _ int) string {
+			return filepath.Base(path)
+		})
+		status += warningStyle.Render(" timed out: " + strings.Join(names, ", "))
+	}
+	return status
+}
+
+// profileStatus names the profile listed, and the error loading it
+// failed with.
+func (m Model) profileStatus() string {
+	if len(m.profiles) < 2 {
+		return ""
+	}
+	name := m.switching.OrElse(m.profile)
+	status := pathStyle.Render(" " + lo.Ternary(name == "", "default", name))
+	if m.profileErr != nil {
+		status += warningStyle.Render(" " + m.profileErr.Error())
+	}
+	return status
+}
+
+// hiddenStatus tells how many listed projects are hidden and which key
+// reveals them.
+func (m Model) hiddenStatus() string {
+	count := lo.CountBy(m.projects, func(p projects.Project) bool { return m.hidden[p.Path] })
+	if count == 0 {
+		return ""
+	}
+	label := fmt.Sprintf(" %d hidden", count)
+	if m.showHidden {
+		label = fmt.Sprintf(" showing %d hidden", count)
+	}
+	return pathStyle.Render(label) + " " + keymapKeyStyle.Render(m.keys.ShowHidden.Help().Key)
+}
+
+func renderHeader(innerWidth int, keys KeyMap, filteredCount, totalCount int, status string) string {
 	title := titleStyle.Render("Projects")
-	counter := pathStyle.Render(fmt.Sprintf(" (%d/%d)", filteredCount, totalCount))
+	counter := pathStyle.Render(fmt.Sprintf(" (%d/%d)", filteredCount, totalCount)) + status
 	escHint := keymapKeyStyle.Render(keys.Cancel.Help().Key)
 	padding := max(innerWidth-lipgloss.Width(title)-lipgloss.Width(counter)-lipgloss.Width(escHint), 1)
 
@@ -216,22 +702,93 @@ func calculateVisibleRange(itemCount, visibleCount, cursor int) (start, end int)
 	return start, end
 }
 
-func renderItem(p projects.Project, isSelected bool, maxName, innerWidth int, icon string) string {
-	name := fmt.Sprintf("%-*s", maxName, p.Name)
+func renderItem(p projects.Project, status projects.Status, isSelected bool, cols columns, innerWidth int, icons Icons) string {
+	name := fmt.Sprintf("%-*s", cols.name, p.Name)
+	head := ""
+	if cols.head > 0 {
+		head = fmt.Sprintf("%-*s ", cols.head, headLabel(p.Head))
+	}
+	state := ""
+	if cols.status > 0 {
+		label := statusLabel(status, icons)
+		state = label + strings.Repeat(" ", cols.status-lipgloss.Width(label)+1)
+	}
 	path := fmt.Sprintf("(%s)", p.Path)
+	tag := renderTag(p, icons)
+	icon := projectIcon(p, icons)
 
 	if isSelected {
-		line := fmt.Sprintf("%s  %s %s", icon, name, path)
+		line := fmt.Sprintf("%s  %s %s%s%s%s", icon, name, head, state, path, tag)
 		return selectedStyle.Render(lipgloss.NewStyle().Width(innerWidth).Render(line))
 	}
 
-	return fmt.Sprintf("%s  %s %s",
+	return fmt.Sprintf("%s  %s %s%s%s%s",
 		normalStyle.Render(icon),
 		normalStyle.Render(name),
+		branchStyle.Render(head),
+		warningStyle.Render(state),
 		pathStyle.Render(path),
+		pathStyle.Render(tag),
 	)
 }
 
+func projectIcon(p projects.Project, icons Icons) string {
+	switch {
+	case p.Hidden:
+		return icons.Hidden
+	case p.Pinned:
+		return icons.Pin
+	case p.Bare:
+		return icons.Bare
+	}
+	return icons.Dir
+}
+
+// statusLabel shows whether a project has uncommitted changes and how far
+// it is ahead of and behind its upstream.
+func statusLabel(s projects.Status, icons Icons) string {
+	var parts []string
+	if s.Dirty {
+		parts = append(parts, icons.Dirty)
+	}
+	if s.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", icons.Ahead, s.Ahead))
+	}
+	if s.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", icons.Behind, s.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// headLabel shows the branch and commit a git project has checked out.
+func headLabel(h projects.Head) string {
+	switch {
+	case h.Detached:
+		return "detached@" + h.Commit
+	case h.Commit != "":
+		return h.Branch + "@" + h.Commit
+	}
+	return h.Branch
+}
+
+// renderTag names where a git project's origin is hosted, and the main
+// repository of a linked worktree or submodule or the workspace root of a
+// workspace member.
+func renderTag(p projects.Project, icons Icons) string {
+	tag := ""
+	if p.Remote != "" {
+		tag = fmt.Sprintf(" %s %s", icons.Remote, p.Remote)
+	}
+
+	switch {
+	case p.MainRepo != "":
+		tag += fmt.Sprintf(" %s %s", icons.Worktree, filepath.Base(p.MainRepo))
+	case p.Parent != "":
+		tag += fmt.Sprintf(" %s %s", icons.Workspace, filepath.Base(p.Parent))
+	}
+	return tag
+}
+
 func renderList(m Model, l layout, filtered []projects.Project, cursor int, fixedHeight int) string {
 	var content string
 	var renderedLines int
@@ -249,11 +806,12 @@ func renderList(m Model, l layout, filtered []projects.Project, cursor int, fixe
 
 		visibleCount := min(len(filtered), listHeight)
 		start, end := calculateVisibleRange(len(filtered), visibleCount, cursor)
-		maxName := maxNameLen(filtered)
+		filtered = m.displayed(filtered)
+		cols := measureColumns(filtered, m.statuses, m.icons)
 
 		for i := start; i < end; i++ {
 			p := filtered[i]
-			b.WriteString(renderItem(p, i == cursor, maxName, l.innerWidth, m.icons.Dir))
+			b.WriteString(renderItem(p, m.statuses[p.Path], i == cursor, cols, l.innerWidth, m.icons))
 			b.WriteString("\n")
 		}
 		content = b.String()
@@ -268,16 +826,32 @@ func renderList(m Model, l layout, filtered []projects.Project, cursor int, fixe
 	return content
 }
 
-func renderFooter(innerWidth int, keys KeyMap) string {
-	hints := fmt.Sprintf("%s %s %s",
-		renderKeyHelp(keys.NextItem),
-		renderKeyHelp(keys.PrevItem),
-		renderKeyHelp(keys.Select),
-	)
+func renderFooter(innerWidth int, keys KeyMap, sort projects.SortMode, profiles bool) string {
+	sortKey := keys.Sort
+	sortKey.SetHelp(sortKey.Help().Key, fmt.Sprintf("%s: %s", sortKey.Help().Desc, sort))
+
+	bindings := []key.Binding{sortKey, keys.Hide}
+	if profiles {
+		bindings = append(bindings, keys.Profile)
+	}
+	bindings = append(bindings, keys.Select, keys.NextItem, keys.PrevItem)
+	hints := renderKeyHints(bindings...)
+	for len(bindings) > 1 && lipgloss.Width(hints) > innerWidth {
+		// Moving through the list needs no explaining, so those hints make
+		// room first, and selecting after them.
+		bindings = bindings[:len(bindings)-1]
+		hints = renderKeyHints(bindings...)
+	}
 
 	return "\n" + lipgloss.PlaceHorizontal(innerWidth, lipgloss.Right, hints)
 }
 
+func renderKeyHints(bindings ...key.Binding) string {
+	return strings.Join(lo.Map(bindings, func(b key.Binding, _ int) string {
+		return renderKeyHelp(b)
+	}), " ")
+}
+
 func renderKeyHelp(binding key.Binding) string {
 	return fmt.Sprintf("%s %s",
 		keymapLabelStyle.Render(binding.Help().Desc),
@@ -285,10 +859,11 @@ func renderKeyHelp(binding key.Binding) string {
 	)
 }
 
-func maxLineWidth(projs []projects.Project) int {
+func maxLineWidth(projs []projects.Project, statuses map[string]projects.Status, icons Icons) int {
+	cols := measureColumns(projs, statuses, icons)
 	maxWidth := 0
 	for _, p := range projs {
-		lineLen := lineWidthBase + len(p.Name) + len(p.Path)
+		lineLen := lineWidthBase + cols.width() + len(p.Path) + lipgloss.Width(renderTag(p, icons))
 		if lineLen > maxWidth {
 			maxWidth = lineLen
 		}
@@ -296,12 +871,31 @@ func maxLineWidth(projs []projects.Project) int {
 	return maxWidth + linePadding
 }
 
-func maxNameLen(projs []projects.Project) int {
-	maxLen := 0
+// columns are the widths the list pads its columns to.
+type columns struct {
+	name   int
+	head   int
+	status int
+}
+
+// width is how much the padded columns take up in front of the path.
+func (c columns) width() int {
+	width := c.name
+	if c.head > 0 {
+		width += c.head + 1
+	}
+	if c.status > 0 {
+		width += c.status + 1
+	}
+	return width
+}
+
+func measureColumns(projs []projects.Project, statuses map[string]projects.Status, icons Icons) columns {
+	var cols columns
 	for _, p := range projs {
-		if len(p.Name) > maxLen {
-			maxLen = len(p.Name)
-		}
+		cols.name = max(cols.name, len(p.Name))
+		cols.head = max(cols.head, len(headLabel(p.Head)))
+		cols.status = max(cols.status, lipgloss.Width(statusLabel(statuses[p.Path], icons)))
 	}
-	return maxLen
+	return cols
 }
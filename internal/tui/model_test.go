@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"dev/internal/projects"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+func update(t *testing.T, m Model, msg tea.Msg) (Model, tea.Cmd) {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	return next.(Model), cmd
+}
+
+func TestModel_RereadUpdatesListedProjects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reread := projects.NewCollector(ctx, func(_ context.Context, p projects.Project) mo.Result[projects.Project] {
+		p.Head = projects.Head{Branch: "feature", Commit: "1a2b3c4"}
+		return mo.Ok(p)
+	}, func(projects.Project) bool { return true }, 1)
+
+	cached := []projects.Project{{Name: "api", Path: "/src/api", VCS: projects.VCSGit, Head: projects.Head{Branch: "main"}}}
+	registered := []projects.Project{{Name: "app", Path: "/srv/app", VCS: projects.VCSGit, Pinned: true}}
+	m := NewModel(nil, DefaultKeyMap(), Icons{}).
+		WithRegistered(registered).
+		WithListing(Listing{Projects: cached}).
+		WithReread(reread)
+
+	var msg rereadMsg
+	for len(msg) < 2 {
+		msg = append(msg, waitForUpdates[projects.Project, rereadMsg](reread)().(rereadMsg)...)
+	}
+	m, _ = update(t, m, msg)
+
+	for _, p := range m.filtered {
+		if p.Head.Branch != "feature" {
+			t.Errorf("expected %s to show the branch read again, got %q", p.Path, p.Head.Branch)
+		}
+	}
+	app, ok := lo.Find(m.filtered, func(p projects.Project) bool { return p.Path == "/srv/app" })
+	if !ok || !app.Pinned {
+		t.Errorf("expected the registered project to stay pinned, got %+v", app)
+	}
+}
+
+func keyMsg(t tea.KeyType) tea.KeyMsg {
+	return tea.KeyMsg{Type: t}
+}
+
+func typed(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func filteredPaths(m Model) []string {
+	return lo.Map(m.filtered, func(p projects.Project, _ int) string { return p.Path })
+}
+
+func hiddenModel(save func(string, bool) error) Model {
+	list := []projects.Project{
+		{Name: "api", Path: "/src/api"},
+		{Name: "api-old", Path: "/src/api-old"},
+		{Name: "web", Path: "/src/web"},
+	}
+	return NewModel(nil, DefaultKeyMap(), Icons{Dir: "d", Hidden: "h"}).
+		WithHidden([]string{"/src/api-old"}, save).
+		WithSort(projects.SortPath).
+		WithListing(Listing{Projects: list})
+}
+
+func TestModel_HiddenProjectsAreLeftOutBeforeFiltering(t *testing.T) {
+	m := hiddenModel(nil)
+
+	if got, want := filteredPaths(m), []string{"/src/api", "/src/web"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v listed, got %v", want, got)
+	}
+
+	m, _ = update(t, m, typed("old"))
+	if got := filteredPaths(m); len(got) != 0 {
+		t.Errorf("expected a query to match no hidden project, got %v", got)
+	}
+	if !strings.Contains(m.hiddenStatus(), "1 hidden") {
+		t.Errorf("expected the header to count the hidden project, got %q", m.hiddenStatus())
+	}
+}
+
+func TestModel_ShowHiddenRevealsThemMarked(t *testing.T) {
+	m := hiddenModel(nil)
+
+	m, _ = update(t, m, keyMsg(tea.KeyCtrlR))
+	if got, want := filteredPaths(m), []string{"/src/api", "/src/api-old", "/src/web"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v listed, got %v", want, got)
+	}
+	for _, p := range m.filtered {
+		icon, want := projectIcon(p, m.icons), lo.Ternary(p.Path == "/src/api-old", "h", "d")
+		if icon != want {
+			t.Errorf("expected %s to show icon %q, got %q", p.Path, want, icon)
+		}
+	}
+	if !strings.Contains(m.hiddenStatus(), "showing 1 hidden") {
+		t.Errorf("expected the header to say hidden projects are shown, got %q", m.hiddenStatus())
+	}
+
+	m, _ = update(t, m, typed("old"))
+	if got, want := filteredPaths(m), []string{"/src/api-old"}; !slices.Equal(got, want) {
+		t.Errorf("expected the revealed project to match a query, got %v", got)
+	}
+
+	m, _ = update(t, m, keyMsg(tea.KeyCtrlR))
+	if got := filteredPaths(m); len(got) != 0 {
+		t.Errorf("expected the project to be hidden again, got %v", got)
+	}
+}
+
+func TestModel_HideSavesEachChange(t *testing.T) {
+	type change struct {
+		path   string
+		hidden bool
+	}
+	var saved []change
+	m := hiddenModel(func(path string, hidden bool) error {
+		saved = append(saved, change{path, hidden})
+		return nil
+	})
+
+	m, cmd := update(t, m, keyMsg(tea.KeyCtrlX))
+	cmd()
+	if got, want := filteredPaths(m), []string{"/src/web"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v listed after hiding the first project, got %v", want, got)
+	}
+
+	m, _ = update(t, m, keyMsg(tea.KeyCtrlR))
+	m, _ = update(t, m, typed("old"))
+	m, cmd = update(t, m, keyMsg(tea.KeyCtrlX))
+	cmd()
+	m, _ = update(t, m, keyMsg(tea.KeyCtrlR))
+	if got, want := filteredPaths(m), []string{"/src/api-old"}; !slices.Equal(got, want) {
+		t.Errorf("expected the project shown again to stay listed, got %v", got)
+	}
+
+	if want := []change{{"/src/api", true}, {"/src/api-old", false}}; !slices.Equal(saved, want) {
+		t.Errorf("expected %v to be saved, got %v", want, saved)
+	}
+}
+
+func TestModel_HideWithoutSaveDoesNothing(t *testing.T) {
+	m := NewModel(nil, DefaultKeyMap(), Icons{}).WithListing(Listing{Projects: []projects.Project{{Name: "api", Path: "/src/api"}}})
+
+	m, cmd := update(t, m, keyMsg(tea.KeyCtrlX))
+	if cmd != nil || len(m.filtered) != 1 {
+		t.Errorf("expected nothing to be hidden, got %v", filteredPaths(m))
+	}
+}
+
+func TestModel_ProfileCyclesThroughListings(t *testing.T) {
+	listings := map[string][]projects.Project{
+		"":     {{Name: "dotfiles", Path: "/home/dotfiles"}},
+		"work": {{Name: "api", Path: "/work/api"}, {Name: "web", Path: "/work/web"}},
+	}
+	var loaded []string
+	load := func(name string) mo.Result[Listing] {
+		loaded = append(loaded, name)
+		return mo.Ok(Listing{Projects: listings[name]})
+	}
+	m := NewModel(nil, DefaultKeyMap(), Icons{}).
+		WithListing(Listing{Projects: listings[""]}).
+		WithProfiles([]string{"", "work"}, "", load)
+
+	m, cmd := update(t, m, keyMsg(tea.KeyCtrlT))
+	if got := filteredPaths(m); !slices.Equal(got, []string{"/home/dotfiles"}) {
+		t.Errorf("expected the listed projects to stay until the next profile's are known, got %v", got)
+	}
+	m, _ = update(t, m, cmd())
+	if m.profile != "work" {
+		t.Errorf("expected the work profile, got %q", m.profile)
+	}
+	if got, want := filteredPaths(m), []string{"/work/api", "/work/web"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v listed, got %v", want, got)
+	}
+
+	m, cmd = update(t, m, keyMsg(tea.KeyCtrlT))
+	m, _ = update(t, m, cmd())
+	if m.profile != "" {
+		t.Errorf("expected to cycle back to no profile, got %q", m.profile)
+	}
+	if got := filteredPaths(m); !slices.Equal(got, []string{"/home/dotfiles"}) {
+		t.Errorf("expected the projects without a profile listed, got %v", got)
+	}
+	if want := []string{"work", ""}; !slices.Equal(loaded, want) {
+		t.Errorf("expected %v to be loaded, got %v", want, loaded)
+	}
+}
+
+func TestModel_ProfileIgnoresStaleListings(t *testing.T) {
+	load := func(name string) mo.Result[Listing] {
+		return mo.Ok(Listing{Projects: []projects.Project{{Name: name, Path: "/" + name}}})
+	}
+	m := NewModel(nil, DefaultKeyMap(), Icons{}).
+		WithListing(Listing{Projects: []projects.Project{{Name: "src", Path: "/src"}}}).
+		WithProfiles([]string{"", "home", "work"}, "", load)
+
+	// Switching on before home is loaded makes its listing stale.
+	m, toHome := update(t, m, keyMsg(tea.KeyCtrlT))
+	m, toWork := update(t, m, keyMsg(tea.KeyCtrlT))
+	m, _ = update(t, m, toHome())
+	if m.profile != "" || !slices.Equal(filteredPaths(m), []string{"/src"}) {
+		t.Errorf("expected the stale home listing to be ignored, got %q listing %v", m.profile, filteredPaths(m))
+	}
+	m, _ = update(t, m, toWork())
+	if m.profile != "work" || !slices.Equal(filteredPaths(m), []string{"/work"}) {
+		t.Errorf("expected work listed, got %q listing %v", m.profile, filteredPaths(m))
+	}
+
+	// Nor does a listing arriving while the picker closes replace it.
+	m, toNone := update(t, m, keyMsg(tea.KeyCtrlT))
+	m, _ = update(t, m, keyMsg(tea.KeyEnter))
+	m, _ = update(t, m, toNone())
+	if m.profile != "work" || !slices.Equal(filteredPaths(m), []string{"/work"}) {
+		t.Errorf("expected work to stay listed while quitting, got %q listing %v", m.profile, filteredPaths(m))
+	}
+}
+
+func TestModel_ProfileLoadErrorKeepsListing(t *testing.T) {
+	load := func(string) mo.Result[Listing] { return mo.Err[Listing](errors.New("unreadable")) }
+	m := NewModel(nil, DefaultKeyMap(), Icons{}).
+		WithListing(Listing{Projects: []projects.Project{{Name: "src", Path: "/src"}}}).
+		WithProfiles([]string{"", "work"}, "", load)
+
+	m, cmd := update(t, m, keyMsg(tea.KeyCtrlT))
+	m, _ = update(t, m, cmd())
+	if m.profile != "" || m.profileErr == nil || !slices.Equal(filteredPaths(m), []string{"/src"}) {
+		t.Errorf("expected the error to be shown over the same list, got %q, %v listing %v", m.profile, m.profileErr, filteredPaths(m))
+	}
+}
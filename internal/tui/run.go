@@ -12,7 +12,13 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func Run(m tea.Model) mo.Result[projects.Project] {
+// Selection is the project picked, and the profile it was listed under.
+type Selection struct {
+	projects.Project
+	Profile string
+}
+
+func Run(m tea.Model) mo.Result[Selection] {
 	program := tea.NewProgram(m,
 		tea.WithAltScreen(),
 		tea.WithInputTTY(),
@@ -21,17 +27,20 @@ func Run(m tea.Model) mo.Result[projects.Project] {
 
 	finalModel, err := program.Run()
 	if err != nil {
-		return mo.Err[projects.Project](fmt.Errorf("tui: %w", err))
+		return mo.Err[Selection](fmt.Errorf("tui: %w", err))
 	}
 
 	model := finalModel.(Model)
+	if model.err != nil {
+		return mo.Err[Selection](model.err)
+	}
 
 	project, ok := lo.Find(model.projects, func(p projects.Project) bool {
 		return p.Path == model.Selected
 	})
 	if !ok {
-		return mo.Err[projects.Project](fmt.Errorf("no project found"))
+		return mo.Err[Selection](fmt.Errorf("no project found"))
 	}
 
-	return mo.Ok(project)
+	return mo.Ok(Selection{Project: project, Profile: model.profile})
 }
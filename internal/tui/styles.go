@@ -1,43 +1,103 @@
 package tui
 
 import (
+	"fmt"
 	"os"
+	"regexp"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
 var renderer = lipgloss.NewRenderer(os.Stderr)
 
+// colors are the theme, by the names config.toml uses for them.
+var colors = map[string]lipgloss.Color{
+	"accent":  lipgloss.Color("4"),
+	"muted":   lipgloss.Color("8"),
+	"text":    lipgloss.Color("15"),
+	"warning": lipgloss.Color("3"),
+	"branch":  lipgloss.Color("5"),
+}
+
 var (
-	blue  = lipgloss.Color("4")
-	gray  = lipgloss.Color("8")
-	white = lipgloss.Color("15")
+	borderStyle      lipgloss.Style
+	inputStyle       lipgloss.Style
+	selectedStyle    lipgloss.Style
+	normalStyle      lipgloss.Style
+	pathStyle        lipgloss.Style
+	titleStyle       lipgloss.Style
+	keymapLabelStyle lipgloss.Style
+	keymapKeyStyle   lipgloss.Style
+	warningStyle     lipgloss.Style
+	branchStyle      lipgloss.Style
+)
+
+func init() {
+	buildStyles()
+}
+
+func buildStyles() {
+	accent, muted, text := colors["accent"], colors["muted"], colors["text"]
 
 	borderStyle = renderer.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(blue).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accent).
+		Padding(1, 2)
 
 	inputStyle = renderer.NewStyle().
-			Foreground(white)
+		Foreground(text)
 
 	selectedStyle = renderer.NewStyle().
-			Foreground(blue).
-			Bold(true)
+		Foreground(accent).
+		Bold(true)
 
 	normalStyle = renderer.NewStyle().
-			Foreground(white)
+		Foreground(text)
 
 	pathStyle = renderer.NewStyle().
-			Foreground(gray)
+		Foreground(muted)
 
 	titleStyle = renderer.NewStyle().
-			Foreground(white).
-			Bold(true)
+		Foreground(text).
+		Bold(true)
 
 	keymapLabelStyle = renderer.NewStyle().
-				Foreground(white)
+		Foreground(text)
 
 	keymapKeyStyle = renderer.NewStyle().
-			Foreground(gray)
-)
+		Foreground(muted)
+
+	warningStyle = renderer.NewStyle().
+		Foreground(colors["warning"])
+
+	branchStyle = renderer.NewStyle().
+		Foreground(colors["branch"])
+}
+
+// colorPattern matches what lipgloss understands as a color: an ANSI color
+// number or a hex code.
+var colorPattern = regexp.MustCompile(`^(\d{1,3}|#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6})$`)
+
+// SetColor changes the theme color called name to value, an ANSI color
+// number such as "4" or a hex code such as "#7aa2f7".
+func SetColor(name, value string) error {
+	if _, ok := colors[name]; !ok {
+		return fmt.Errorf("unknown theme color %q", name)
+	}
+	if !colorPattern.MatchString(value) {
+		return fmt.Errorf("invalid color %q for %s", value, name)
+	}
+
+	colors[name] = lipgloss.Color(value)
+	buildStyles()
+	return nil
+}
+
+// Colors returns the theme colors by name.
+func Colors() map[string]string {
+	theme := make(map[string]string, len(colors))
+	for name, c := range colors {
+		theme[name] = string(c)
+	}
+	return theme
+}
@@ -0,0 +1,63 @@
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/samber/mo"
+)
+
+// CacheHome returns the directory dev keeps disposable data in, following
+// $XDG_CACHE_HOME and falling back to ~/.cache.
+func CacheHome() mo.Result[string] {
+	if dir := os.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+		return mo.Ok(filepath.Join(dir, "dev"))
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(filepath.Join(home, ".cache", "dev"))
+}
+
+// ConfigHome returns the directory dev reads its configuration from,
+// following $XDG_CONFIG_HOME and falling back to ~/.config on every
+// platform, as command line tools commonly do.
+func ConfigHome() mo.Result[string] {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return mo.Ok(filepath.Join(dir, "dev"))
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(filepath.Join(home, ".config", "dev"))
+}
+
+// StateHome returns the directory dev keeps data in that should outlive the
+// cache but is not configuration, following $XDG_STATE_HOME and falling
+// back to ~/.local/state.
+func StateHome() mo.Result[string] {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return mo.Ok(filepath.Join(dir, "dev"))
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return mo.Err[string](err)
+	}
+	return mo.Ok(filepath.Join(home, ".local", "state", "dev"))
+}
+
+// RuntimeDir returns the directory dev keeps sockets in, following
+// $XDG_RUNTIME_DIR and falling back to a per-user directory in the
+// temporary directory.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "dev")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("dev-%d", os.Getuid()))
+}
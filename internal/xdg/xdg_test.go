@@ -0,0 +1,83 @@
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/mo"
+)
+
+func TestHomes(t *testing.T) {
+	homes := []struct {
+		variable string
+		home     func() mo.Result[string]
+		fallback string
+	}{
+		{variable: "XDG_CACHE_HOME", home: CacheHome, fallback: "/home/dev/.cache/dev"},
+		{variable: "XDG_CONFIG_HOME", home: ConfigHome, fallback: "/home/dev/.config/dev"},
+		{variable: "XDG_STATE_HOME", home: StateHome, fallback: "/home/dev/.local/state/dev"},
+	}
+	tests := []struct {
+		name   string
+		value  string
+		xdgDir bool
+	}{
+		{name: "absolute", value: "/xdg", xdgDir: true},
+		{name: "empty", value: ""},
+		{name: "relative", value: "xdg"},
+		{name: "dot relative", value: "./xdg"},
+	}
+
+	for _, h := range homes {
+		for _, tt := range tests {
+			t.Run(h.variable+" "+tt.name, func(t *testing.T) {
+				t.Setenv("HOME", "/home/dev")
+				t.Setenv(h.variable, tt.value)
+
+				got, err := h.home().Get()
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				want := h.fallback
+				if tt.xdgDir {
+					want = "/xdg/dev"
+				}
+				if got != want {
+					t.Errorf("expected %s, got %s", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestRuntimeDir(t *testing.T) {
+	fallback := filepath.Join(os.TempDir(), fmt.Sprintf("dev-%d", os.Getuid()))
+
+	tests := []struct {
+		name  string
+		value string
+		unset bool
+		want  string
+	}{
+		{name: "absolute", value: "/run/user/1000", want: "/run/user/1000/dev"},
+		{name: "unset", unset: true, want: fallback},
+		{name: "empty", value: "", want: fallback},
+		{name: "relative", value: "run", want: fallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_RUNTIME_DIR", tt.value)
+			if tt.unset {
+				if err := os.Unsetenv("XDG_RUNTIME_DIR"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := RuntimeDir(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,19 +4,89 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"dev/internal/app"
+	"dev/internal/config"
 	"dev/internal/filesystem"
-	"dev/internal/terminal"
+	"dev/internal/projects"
 	"dev/internal/tui"
+
+	"github.com/samber/mo"
 )
 
 var version string
 
+// commands are run instead of the picker when named by the first arguments.
+var commands = map[string]func(app.Config) mo.Result[string]{
+	"daemon":      app.Daemon,
+	"add":         app.Add,
+	"remove":      app.Remove,
+	"pin":         app.Pin,
+	"hide":        app.Hide,
+	"config show": app.ConfigShow,
+}
+
 func main() {
 	var printVersion bool
 	var printPath bool
 	var noUpdateTitle bool
+	var maxDepth mo.Option[int]
+	var markers []string
+	var nested bool
+	var noWorkspaces bool
+	var refresh bool
+	var scanTimeout time.Duration
+	var excludes []string
+	var hiddenDirs []string
+	var hidden bool
+	var follow bool
+	var diagnose bool
+	var jobs int
+	var noGitInfo bool
+	var noStatus bool
+	sortMode := projects.SortScore
+	var profile mo.Option[string]
+
+	parseMaxDepth := func(s string) error {
+		depth, err := strconv.Atoi(s)
+		if err != nil || depth < 0 {
+			return fmt.Errorf("must be a non-negative integer")
+		}
+		maxDepth = mo.Some(depth)
+		return nil
+	}
+
+	parseSort := func(s string) error {
+		mode, err := projects.ParseSortMode(s).Get()
+		if err != nil {
+			return err
+		}
+		sortMode = mode
+		return nil
+	}
+
+	parseProfile := func(s string) error {
+		profile = mo.Some(s)
+		return nil
+	}
+
+	appendMarker := func(s string) error {
+		markers = append(markers, s)
+		return nil
+	}
+
+	appendExclude := func(s string) error {
+		excludes = append(excludes, s)
+		return nil
+	}
+
+	appendHiddenDir := func(s string) error {
+		hiddenDirs = append(hiddenDirs, s)
+		return nil
+	}
 
 	flag.BoolVar(&printVersion, "v", false, "print version")
 	flag.BoolVar(&printVersion, "version", false, "print version")
@@ -24,12 +94,54 @@ func main() {
 	flag.BoolVar(&printPath, "print-path", false, "print selected project path to stdout")
 	flag.BoolVar(&noUpdateTitle, "n", false, "do not update terminal tab title")
 	flag.BoolVar(&noUpdateTitle, "no-update-title", false, "do not update terminal tab title")
+	flag.Func("d", "maximum directory depth below each search path", parseMaxDepth)
+	flag.Func("max-depth", "maximum directory depth below each search path", parseMaxDepth)
+	flag.Func("m", "file name pattern that marks a project, may be repeated", appendMarker)
+	flag.Func("marker", "file name pattern that marks a project, may be repeated", appendMarker)
+	flag.Func("x", "pattern of directories to skip, may be repeated", appendExclude)
+	flag.Func("exclude", "pattern of directories to skip, may be repeated", appendExclude)
+	flag.Func("hidden-dir", "hidden directory name to search, may be repeated", appendHiddenDir)
+	flag.BoolVar(&hidden, "hidden", false, "search all hidden directories")
+	flag.BoolVar(&follow, "L", false, "follow symlinked directories")
+	flag.BoolVar(&follow, "follow-symlinks", false, "follow symlinked directories")
+	flag.BoolVar(&nested, "nested", false, "keep searching inside projects for sub-projects")
+	flag.BoolVar(&noWorkspaces, "no-workspaces", false, "do not list workspace members as projects")
+	flag.BoolVar(&refresh, "refresh", false, "ignore cached projects and list only what a fresh scan finds")
+	flag.IntVar(&jobs, "j", 0, "number of directories to read at once (0 means a few per CPU)")
+	flag.IntVar(&jobs, "jobs", 0, "number of directories to read at once (0 means a few per CPU)")
+	flag.BoolVar(&noGitInfo, "no-git-info", false, "do not read the branch and commit of git projects")
+	flag.BoolVar(&noStatus, "no-status", false, "do not show uncommitted changes and unpushed commits of git projects")
+	flag.Func("sort", "order of the list: score, recent, name or path (default score)", parseSort)
+	flag.Func("profile", "profile from config.toml to search with, overriding DEV_PROFILE", parseProfile)
+	flag.BoolVar(&diagnose, "diagnose", false, "print what a scan finds, fails on and skips instead of opening the picker")
+	flag.DurationVar(&scanTimeout, "scan-timeout", 0, "stop scanning after this long, e.g. 5s (0 means no limit)")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: dev [options] [path...]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: dev [options] [path...]\n")
+		fmt.Fprintf(os.Stderr, "       dev daemon [options] [path...]\n")
+		fmt.Fprintf(os.Stderr, "       dev add|remove|hide <path>\n")
+		fmt.Fprintf(os.Stderr, "       dev pin [path]\n")
+		fmt.Fprintf(os.Stderr, "       dev config show [options] [path...]\n\n")
+		fmt.Fprintf(os.Stderr, "Commands:\n")
+		fmt.Fprintf(os.Stderr, "  daemon\tkeep the projects indexed so dev with the same options starts instantly\n")
+		fmt.Fprintf(os.Stderr, "  add\tlist a directory as a project wherever it is\n")
+		fmt.Fprintf(os.Stderr, "  remove\tstop listing a directory added or pinned before\n")
+		fmt.Fprintf(os.Stderr, "  pin\tlist a directory, or the current one, first\n")
+		fmt.Fprintf(os.Stderr, "  hide\tleave a project out of the list, ctrl+r in the list shows it again\n")
+		fmt.Fprintf(os.Stderr, "  config show\tprint the configuration in effect and where it comes from\n\n")
+		fmt.Fprintf(os.Stderr, "To search a directory named like a command, write it as ./name or after --.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
-	flag.Parse()
+
+	run := app.Run
+	args := os.Args[1:]
+	for words := min(len(args), 2); words > 0; words-- {
+		if command, ok := commands[strings.Join(args[:words], " ")]; ok {
+			run, args = command, args[words:]
+			break
+		}
+	}
+	_ = flag.CommandLine.Parse(args)
 
 	if printVersion {
 		if version == "" {
@@ -39,21 +151,60 @@ func main() {
 		os.Exit(0)
 	}
 
+	file, err := config.Load().Get()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	cfg := app.Config{
 		Args: flag.Args(),
 		Flags: app.Flags{
 			PrintPath:     printPath,
 			NoUpdateTitle: noUpdateTitle,
+			MaxDepth:      maxDepth,
+			Markers:       markers,
+			Nested:        nested,
+			NoWorkspaces:  noWorkspaces,
+			Refresh:       refresh,
+			ScanTimeout:   scanTimeout,
+			Excludes:      excludes,
+			HiddenDirs:    hiddenDirs,
+			Hidden:        hidden,
+			Follow:        follow,
+			Diagnose:      diagnose,
+			Jobs:          jobs,
+			NoGitInfo:     noGitInfo,
+			NoStatus:      noStatus,
+			Sort:          sortMode,
+			Profile:       profile,
 		},
-		Term: terminal.Detect(),
+		Keys: tui.DefaultKeyMap(),
 		Fs:   &filesystem.RealFileSystem{},
+		File: file,
 		Icons: tui.Icons{
-			Dir:  "",
-			Term: "",
+			Dir:       "",
+			Term:      "",
+			Worktree:  "",
+			Workspace: "",
+			Warning:   "",
+			Bare:      "",
+			Dirty:     "",
+			Ahead:     "↑",
+			Behind:    "↓",
+			Remote:    "",
+			Pin:       "",
+			Hidden:    "",
 		},
 	}
 
-	res, err := app.Run(cfg).Get()
+	cfg, err = app.Configure(cfg).Get()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	res, err := run(cfg).Get()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)